@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ashish246/GolangGitExample/src/gitstore"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/yaml.v2"
+)
+
+// AuditEntitlements writes a chronological report of the edges added to and
+// removed from opts.File by every commit of the configured branch that
+// changed it, with the commit hash, author and date.
+func AuditEntitlements(opts auditOptions, w io.Writer) error {
+	// Keep the clone progress out of the report
+	store := opts.storeOptions()
+	store.Progress = nil
+	repo, err := gitstore.Open(store)
+	if err != nil {
+		return err
+	}
+	revisions, err := repo.History(opts.File)
+	if err != nil {
+		return err
+	}
+	if len(revisions) == 0 {
+		return fmt.Errorf("%s has no history on %s", opts.File, opts.Branch)
+	}
+
+	fmt.Fprintf(w, "History of %s on %s", opts.File, opts.Branch)
+	if filter := opts.Filter.String(); filter != "" {
+		fmt.Fprintf(w, " for %s", filter)
+	}
+	fmt.Fprintln(w)
+
+	previous := map[Edge]bool{}
+	for _, rev := range revisions {
+		var current LdapGroupEntitlements
+		if err := yaml.Unmarshal(rev.Content, &current); err != nil {
+			// Keep comparing against the last revision that parsed
+			fmt.Fprintf(w, "\n%s\n  ! unparseable revision: %v\n", commitLine(rev.Commit), err)
+			continue
+		}
+		edges := current.Edges()
+		added, removed := diffEdges(previous, edges)
+		previous = edges
+
+		added, removed = opts.Filter.apply(added), opts.Filter.apply(removed)
+		if len(added) == 0 && len(removed) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s\n", commitLine(rev.Commit))
+		for _, e := range added {
+			fmt.Fprintf(w, "  + %s\n", e)
+		}
+		for _, e := range removed {
+			fmt.Fprintf(w, "  - %s\n", e)
+		}
+	}
+	return nil
+}
+
+// commitLine describes c by its author date, hash and author.
+func commitLine(c *object.Commit) string {
+	return fmt.Sprintf("%s %s %s <%s>", c.Author.When.UTC().Format(time.RFC3339), c.Hash, c.Author.Name, c.Author.Email)
+}
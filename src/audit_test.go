@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ashish246/GolangGitExample/src/gitstore"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// entitlementsYAML renders an entitlements file granting every edge, given
+// as group/role/entitlement group/entitlement.
+func entitlementsYAML(edges ...string) []byte {
+	var b strings.Builder
+	b.WriteString("version: '1.0'\nldap_groups:\n")
+	for _, edge := range edges {
+		p := strings.Split(edge, "/")
+		fmt.Fprintf(&b, "    - name: %s\n      roles:\n        - name: %s\n          entitlement_groups:\n            - name: %s\n              entitlements:\n                - %s\n", p[0], p[1], p[2], p[3])
+	}
+	return []byte(b.String())
+}
+
+func TestAuditEntitlements(t *testing.T) {
+	url := newRemote(t, map[string][]byte{"README.md": []byte("policies\n")})
+	day := 0
+	commit := func(name string, content []byte) *object.Commit {
+		day++
+		repo, err := gitstore.Open(gitstore.Options{URL: url, Branch: "master"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := repo.CommitFiles(map[string][]byte{name: content}, gitstore.CommitOptions{
+			Author:  &object.Signature{Name: "Policy Bot", Email: "policy-bot@example.com", When: time.Date(2020, 1, day, 0, 0, 0, 0, time.UTC)},
+			Message: gitstore.Message(fmt.Sprintf("Change %d", day)),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.Push(); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	line := func(c *object.Commit) string {
+		return fmt.Sprintf("%s %s Policy Bot <policy-bot@example.com>", c.Author.When.UTC().Format(time.RFC3339), c.Hash)
+	}
+
+	const file = "entitlements.yml"
+	granted := commit(file, entitlementsYAML("support/agent/tickets/read"))
+	commit("README.md", []byte("policies, updated\n"))
+	extended := commit(file, entitlementsYAML("support/agent/tickets/read", "support/agent/tickets/write", "batch/runner/jobs/read"))
+	revoked := commit(file, entitlementsYAML("support/agent/tickets/write", "batch/runner/jobs/read"))
+	broken := commit(file, []byte("ldap_groups: [\n"))
+	dropped := commit(file, entitlementsYAML("support/agent/tickets/write"))
+
+	tests := []struct {
+		name   string
+		filter edgeFilter
+		want   []string
+	}{
+		{
+			name: "everything",
+			want: []string{
+				"History of entitlements.yml on master",
+				"",
+				line(granted),
+				"  + support -> agent -> tickets -> read",
+				"",
+				line(extended),
+				"  + batch -> runner -> jobs -> read",
+				"  + support -> agent -> tickets -> write",
+				"",
+				line(revoked),
+				"  - support -> agent -> tickets -> read",
+				"",
+				line(broken),
+				"  ! unparseable revision: ",
+				"",
+				line(dropped),
+				"  - batch -> runner -> jobs -> read",
+			},
+		},
+		{
+			name:   "one group",
+			filter: edgeFilter{Group: "batch"},
+			want: []string{
+				`History of entitlements.yml on master for group "batch"`,
+				"",
+				line(extended),
+				"  + batch -> runner -> jobs -> read",
+				"",
+				line(broken),
+				"  ! unparseable revision: ",
+				"",
+				line(dropped),
+				"  - batch -> runner -> jobs -> read",
+			},
+		},
+		{
+			name:   "one group and entitlement",
+			filter: edgeFilter{Group: "support", Entitlement: "read"},
+			want: []string{
+				`History of entitlements.yml on master for group "support" and entitlement "read"`,
+				"",
+				line(granted),
+				"  + support -> agent -> tickets -> read",
+				"",
+				line(revoked),
+				"  - support -> agent -> tickets -> read",
+				"",
+				line(broken),
+				"  ! unparseable revision: ",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := auditOptions{File: file, Filter: tt.filter}
+			opts.URL, opts.Branch = url, "master"
+			var out bytes.Buffer
+			if err := AuditEntitlements(opts, &out); err != nil {
+				t.Fatalf("AuditEntitlements: %v", err)
+			}
+			got := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+			if len(got) != len(tt.want) {
+				t.Fatalf("printed\n%s\nwant\n%s", out.String(), strings.Join(tt.want, "\n"))
+			}
+			for i := range got {
+				// The parse error is the yaml package's to word
+				if !strings.HasPrefix(got[i], tt.want[i]) || (got[i] != tt.want[i] && !strings.HasSuffix(tt.want[i], ": ")) {
+					t.Errorf("line %d = %q, want %q", i+1, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+
+	opts := auditOptions{File: "missing.yml"}
+	opts.URL, opts.Branch = url, "master"
+	if err := AuditEntitlements(opts, &bytes.Buffer{}); err == nil {
+		t.Error("AuditEntitlements of a file without history succeeded")
+	}
+}
@@ -0,0 +1,968 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ashish246/GolangGitExample/src/credentials"
+	"github.com/ashish246/GolangGitExample/src/gitstore"
+	"github.com/ashish246/GolangGitExample/src/ldapsync"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+)
+
+const usage = `Usage: %s [-config file] <command> <subcommand> [flags]
+
+Commands:
+  ldap users            Print the user entries of the directory as JSON
+  ldap groups           Print the group entries of the directory as JSON
+  ldap sync             Update the user and group snapshots from the directory
+  ldap export           Write new user and group snapshots of the directory
+  ldap lookup           Print the groups of a user or the members of a group from the snapshots
+  ldap check            Report memberships the user and group snapshots disagree on
+  git fetch             Copy the policy files matching -include and not -exclude into the staging folder
+  git update            Append a line to a file in the repo, commit and push it
+  git temp              Create an in-memory repo with one commit and push it
+  git publish           Replace generated files in the repo in one commit and push it
+  bundle build          Build the OPA bundle tarball from data and policy files
+  bundle watch          Rebuild the bundle whenever the policy files change in git
+  entitlements parse    Parse an entitlements file and print a summary
+  entitlements audit    Report when entitlements were granted and revoked in git
+  entitlements diff     Compare the entitlements of two refs or two files
+  entitlements user     Print the entitlements a user gets through their groups, nested included
+
+Flag defaults come from the config file, which defaults to $OPA_CONFIG.
+Every config key can be overridden with an environment variable named
+after it, e.g. git.url is overridden by OPA_GIT_URL.
+
+Secrets are never passed as flags. They are read by the provider set in
+credentials.provider: from OPA_SECRET_<NAME> variables (env) or from one
+file per secret in credentials.dir (file). The names are git_password,
+github_token, ssh_private_key, ssh_key_passphrase, ldap_bind_password,
+gpg_signing_key, gpg_key_passphrase and webhook_secret.
+
+Run '%s <command> <subcommand> -h' for the flags of a subcommand.
+`
+
+// commands maps a command and subcommand to its handler. Each handler
+// receives the loaded config and the arguments following the subcommand name.
+var commands = map[string]map[string]func(cfg *Config, args []string) error{
+	"ldap": {
+		"users":  runLdapUsers,
+		"groups": runLdapGroups,
+		"sync":   runLdapSync,
+		"export": runLdapExport,
+		"lookup": runLdapLookup,
+		"check":  runLdapCheck,
+	},
+	"git": {
+		"fetch":   runGitFetch,
+		"update":  runGitUpdate,
+		"temp":    runGitTemp,
+		"publish": runGitPublish,
+	},
+	"bundle": {
+		"build": runBundleBuild,
+		"watch": runBundleWatch,
+	},
+	"entitlements": {
+		"parse": runEntitlementsParse,
+		"audit": runEntitlementsAudit,
+		"diff":  runEntitlementsDiff,
+		"user":  runEntitlementsUser,
+	},
+}
+
+// errUsage is returned when the command line does not name a known
+// subcommand. The usage text has already been printed at that point.
+var errUsage = errors.New("invalid command")
+
+func run(args []string) error {
+	global := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	global.Usage = printUsage
+	configFile := global.String("config", os.Getenv(envPrefix+"CONFIG"), "path of the YAML config file")
+	if err := global.Parse(args); err != nil {
+		return err
+	}
+	args = global.Args()
+
+	if len(args) < 2 {
+		printUsage()
+		return errUsage
+	}
+
+	subcommands, ok := commands[args[0]]
+	if !ok {
+		printUsage()
+		return errUsage
+	}
+	handler, ok := subcommands[args[1]]
+	if !ok {
+		var names []string
+		for name := range subcommands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Fprintf(os.Stderr, "unknown %s subcommand %q, expected one of: %s\n",
+			args[0], args[1], strings.Join(names, ", "))
+		return errUsage
+	}
+
+	cfg, err := LoadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+	return handler(cfg, args[2:])
+}
+
+func printUsage() {
+	fmt.Fprintf(os.Stderr, usage, os.Args[0], os.Args[0])
+}
+
+// newFlagSet returns a flag set named after the command and subcommand that
+// reports parse errors instead of exiting.
+func newFlagSet(command, subcommand string) *flag.FlagSet {
+	return flag.NewFlagSet(command+" "+subcommand, flag.ContinueOnError)
+}
+
+// ldapOptions holds the connection and search settings shared by the ldap
+// subcommands.
+type ldapOptions struct {
+	URL               string
+	Host              string
+	Port              int
+	StartTLS          bool
+	TLS               ldapsync.TLSOptions
+	AllowInsecureBind bool
+	PageSize          int
+	BindDN            string
+	Password          credentials.Secret
+	UserBaseDN        string
+	GroupBaseDN       string
+	Filter            string
+	NestedGroups      string
+
+	tlsConfig *tls.Config
+}
+
+// register adds the connection flags and -filter. The subcommands add the
+// base DN flags they use.
+func (o *ldapOptions) register(fs *flag.FlagSet, cfg *Config) {
+	fs.StringVar(&o.URL, "url", cfg.Ldap.URL, "ldap:// or ldaps:// URL of the server, replaces -host and -port")
+	fs.StringVar(&o.Host, "host", cfg.Ldap.Host, "LDAP server host")
+	fs.IntVar(&o.Port, "port", cfg.Ldap.Port, "LDAP server port")
+	fs.BoolVar(&o.StartTLS, "start-tls", cfg.Ldap.StartTLS, "upgrade an ldap:// connection to TLS before binding")
+	fs.StringVar(&o.TLS.CAFile, "ca-file", cfg.Ldap.CAFile, "PEM certificate authorities trusted for the server certificate")
+	fs.StringVar(&o.TLS.ServerName, "server-name", cfg.Ldap.ServerName, "host name to verify the server certificate against")
+	fs.StringVar(&o.TLS.CertFile, "client-cert", cfg.Ldap.ClientCertFile, "PEM client certificate")
+	fs.StringVar(&o.TLS.KeyFile, "client-key", cfg.Ldap.ClientKeyFile, "PEM client key")
+	fs.IntVar(&o.PageSize, "page-size", cfg.Ldap.PageSize, "entries requested per search page, -1 to disable paging")
+	fs.BoolVar(&o.AllowInsecureBind, "allow-insecure-bind", cfg.Ldap.AllowInsecureBind, "allow sending the bind password without TLS")
+	fs.StringVar(&o.BindDN, "bind-dn", cfg.Ldap.BindDN, "DN to bind as")
+	fs.StringVar(&o.Filter, "filter", cfg.Ldap.Filter, "search filter")
+	fs.StringVar(&o.NestedGroups, "nested-groups", cfg.Ldap.NestedGroups, "how nested groups are resolved: auto, in_chain or walk")
+}
+
+// resolve reads the bind password from the credentials provider and loads
+// the TLS certificates.
+func (o *ldapOptions) resolve(cfg *Config) error {
+	switch o.NestedGroups {
+	case ldapsync.NestedAuto, ldapsync.NestedInChain, ldapsync.NestedWalk:
+	default:
+		return fmt.Errorf("-nested-groups must be auto, in_chain or walk: %q", o.NestedGroups)
+	}
+	password, err := cfg.SecretProvider().Secret(credentials.LdapBindPassword)
+	if err != nil {
+		return fmt.Errorf("failed to load LDAP bind password: %v", err)
+	}
+	o.Password = password
+	tlsConfig, err := o.TLS.Config()
+	if err != nil {
+		return err
+	}
+	o.tlsConfig = tlsConfig
+	return nil
+}
+
+// directory returns the directory to search.
+func (o ldapOptions) directory() *ldapsync.Directory {
+	return ldapsync.New(ldapsync.Options{
+		URL:               o.URL,
+		Host:              o.Host,
+		Port:              o.Port,
+		StartTLS:          o.StartTLS,
+		TLS:               o.tlsConfig,
+		AllowInsecureBind: o.AllowInsecureBind,
+		PageSize:          o.PageSize,
+		BindDN:            o.BindDN,
+		Password:          o.Password,
+		UserBaseDN:        o.UserBaseDN,
+		GroupBaseDN:       o.GroupBaseDN,
+		NestedGroups:      o.NestedGroups,
+	})
+}
+
+// syncOptions configures SyncSnapshots.
+type syncOptions struct {
+	ldapOptions
+	UsersFile    string
+	GroupsFile   string
+	StateFile    string
+	FullInterval time.Duration
+	Full         bool
+}
+
+func (o *syncOptions) register(fs *flag.FlagSet, cfg *Config) {
+	o.ldapOptions.register(fs, cfg)
+	fs.StringVar(&o.UserBaseDN, "user-base-dn", cfg.Ldap.UserBaseDN, "base DN of the user search")
+	fs.StringVar(&o.GroupBaseDN, "group-base-dn", cfg.Ldap.GroupBaseDN, "base DN of the group search")
+	fs.StringVar(&o.UsersFile, "users", cfg.Snapshot.UsersFile, "user snapshot to update")
+	fs.StringVar(&o.GroupsFile, "groups", cfg.Snapshot.GroupsFile, "group snapshot to update")
+	fs.StringVar(&o.StateFile, "state", cfg.Snapshot.StateFile, "file recording the time of the last full sync")
+	fs.DurationVar(&o.FullInterval, "full-interval", cfg.Snapshot.FullSyncInterval, "read the whole directory when the last full sync is older, 0 to always")
+	fs.BoolVar(&o.Full, "full", false, "read the whole directory and drop deleted entries")
+}
+
+func (o *syncOptions) resolve(cfg *Config) error {
+	if o.FullInterval < 0 {
+		return fmt.Errorf("-full-interval must not be negative: %s", o.FullInterval)
+	}
+	return o.ldapOptions.resolve(cfg)
+}
+
+// exportOptions configures ExportSnapshots.
+type exportOptions struct {
+	ldapOptions
+	UsersFile  string
+	GroupsFile string
+}
+
+func (o *exportOptions) register(fs *flag.FlagSet, cfg *Config) {
+	o.ldapOptions.register(fs, cfg)
+	fs.StringVar(&o.UserBaseDN, "user-base-dn", cfg.Ldap.UserBaseDN, "base DN of the user search")
+	fs.StringVar(&o.GroupBaseDN, "group-base-dn", cfg.Ldap.GroupBaseDN, "base DN of the group search")
+	fs.StringVar(&o.UsersFile, "users", cfg.Snapshot.UsersFile, "user snapshot to write")
+	fs.StringVar(&o.GroupsFile, "groups", cfg.Snapshot.GroupsFile, "group snapshot to write")
+}
+
+// lookupOptions configures LookupSnapshot. Exactly one of User, Account,
+// Group and GroupUUID is set.
+type lookupOptions struct {
+	UsersFile  string
+	GroupsFile string
+	User       string
+	Account    string
+	Group      string
+	GroupUUID  string
+	Nested     bool
+}
+
+func (o *lookupOptions) register(fs *flag.FlagSet, cfg *Config) {
+	fs.StringVar(&o.UsersFile, "users", cfg.Snapshot.UsersFile, "user snapshot")
+	fs.StringVar(&o.GroupsFile, "groups", cfg.Snapshot.GroupsFile, "group snapshot")
+	fs.StringVar(&o.User, "user", "", "common name of the user whose groups are printed")
+	fs.StringVar(&o.Account, "account", "", "sAMAccountName of the user whose groups are printed")
+	fs.StringVar(&o.Group, "group", "", "common name of the group whose members are printed")
+	fs.StringVar(&o.GroupUUID, "group-uuid", "", "entryUUID of the group whose members are printed")
+	fs.BoolVar(&o.Nested, "nested", false, "include the groups of groups and the members of member groups")
+}
+
+func (o *lookupOptions) resolve() error {
+	set := 0
+	for _, v := range []string{o.User, o.Account, o.Group, o.GroupUUID} {
+		if v != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("exactly one of -user, -account, -group and -group-uuid is required")
+	}
+	return nil
+}
+
+// checkOptions configures CheckSnapshots.
+type checkOptions struct {
+	UsersFile  string
+	GroupsFile string
+}
+
+func (o *checkOptions) register(fs *flag.FlagSet, cfg *Config) {
+	fs.StringVar(&o.UsersFile, "users", cfg.Snapshot.UsersFile, "user snapshot")
+	fs.StringVar(&o.GroupsFile, "groups", cfg.Snapshot.GroupsFile, "group snapshot")
+}
+
+// gitOptions holds the repository settings shared by the git subcommands.
+type gitOptions struct {
+	URL          string
+	Branch       string
+	Username     string
+	CacheDir     string
+	Depth        int
+	SingleBranch bool
+	Auth         transport.AuthMethod
+	Retry        gitstore.RetryPolicy
+}
+
+func (o *gitOptions) register(fs *flag.FlagSet, cfg *Config) {
+	fs.StringVar(&o.URL, "url", cfg.Git.URL, "URL of the policy repository")
+	fs.StringVar(&o.Branch, "branch", cfg.Git.Branch, "branch to check out")
+	fs.StringVar(&o.Username, "username", cfg.Git.Username, "username for basic or ssh auth")
+	fs.StringVar(&o.CacheDir, "cache-dir", cfg.Git.CacheDir, "folder reused between runs for the clone, empty to clone into memory")
+	fs.IntVar(&o.Depth, "depth", cfg.Git.Depth, "number of commits of history to fetch, 0 for all")
+	fs.BoolVar(&o.SingleBranch, "single-branch", cfg.Git.SingleBranch, "clone only the checked out branch")
+}
+
+// resolve loads the auth method selected by git.auth once the flags have
+// been parsed.
+func (o *gitOptions) resolve(cfg *Config) error {
+	if err := validateGitURL(o.URL); err != nil {
+		return err
+	}
+	if o.Depth < 0 {
+		return fmt.Errorf("-depth must not be negative: %d", o.Depth)
+	}
+	if o.Depth > 0 && o.CacheDir != "" {
+		return fmt.Errorf("-depth must be 0 with -cache-dir, a shallow cache cannot be fetched into")
+	}
+	auth, err := cfg.GitAuth(o.Username)
+	if err != nil {
+		return err
+	}
+	o.Auth = auth
+	o.Retry = gitstore.RetryPolicy{
+		Attempts:   cfg.Git.PushAttempts,
+		Backoff:    cfg.Git.PushBackoff,
+		MaxBackoff: 30 * time.Second,
+	}
+	return nil
+}
+
+func (o gitOptions) storeOptions() gitstore.Options {
+	return gitstore.Options{
+		URL:          o.URL,
+		Branch:       o.Branch,
+		Auth:         o.Auth,
+		Progress:     os.Stdout,
+		Retry:        o.Retry,
+		CacheDir:     o.CacheDir,
+		Depth:        o.Depth,
+		SingleBranch: o.SingleBranch,
+	}
+}
+
+// fetchOptions configures FetchGitFile.
+type fetchOptions struct {
+	gitOptions
+	// Name, Path and Root are only set for the bundle.sources, see
+	// PolicySource.
+	Name          string
+	Path          string
+	Root          string
+	Ref           string
+	Include       []string
+	Exclude       []string
+	StagingDir    string
+	VerifyKeyRing string
+}
+
+func (o *fetchOptions) register(fs *flag.FlagSet, cfg *Config) {
+	o.gitOptions.register(fs, cfg)
+	fs.StringVar(&o.Ref, "ref", cfg.Bundle.Ref, "tag, branch or full commit hash to take the files from, empty for the tip of -branch")
+	o.Include = cfg.Bundle.Include
+	o.Exclude = cfg.Bundle.Exclude
+	fs.Var((*listFlag)(&o.Include), "include", "comma separated globs of the policy files to copy, e.g. uam2/**/*.rego")
+	fs.Var((*listFlag)(&o.Exclude), "exclude", "comma separated globs of files not to copy")
+	fs.StringVar(&o.VerifyKeyRing, "verify-keyring", cfg.Git.VerifyKeyRing, "armored OpenPGP keyring the HEAD commit signature must validate against, empty to skip")
+}
+
+func (o *fetchOptions) resolve(cfg *Config) error {
+	if err := o.gitOptions.resolve(cfg); err != nil {
+		return err
+	}
+	if len(o.Include) == 0 {
+		return errors.New("-include must name at least one glob")
+	}
+	for _, pattern := range append(append([]string(nil), o.Include...), o.Exclude...) {
+		if err := gitstore.ValidatePattern(pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tempOptions configures makeTempRepo.
+type tempOptions struct {
+	gitOptions
+	commitOptions
+	File string
+	Line string
+}
+
+func (o *tempOptions) register(fs *flag.FlagSet, cfg *Config, line string) {
+	o.gitOptions.register(fs, cfg)
+	o.commitOptions.register(fs, cfg, "Golang Test Commit")
+	fs.StringVar(&o.File, "file", "README.md", "path of the file to write in the repository")
+	fs.StringVar(&o.Line, "line", line, "content to write to the file")
+}
+
+func (o *tempOptions) resolve(cfg *Config) error {
+	if err := o.gitOptions.resolve(cfg); err != nil {
+		return err
+	}
+	return o.commitOptions.resolve(cfg)
+}
+
+// updateOptions configures UpdateGitFile.
+type updateOptions struct {
+	tempOptions
+	reviewOptions
+}
+
+func (o *updateOptions) register(fs *flag.FlagSet, cfg *Config, line string) {
+	o.tempOptions.register(fs, cfg, line)
+	o.reviewOptions.register(fs, cfg)
+}
+
+func (o *updateOptions) resolve(cfg *Config) error {
+	if err := o.tempOptions.resolve(cfg); err != nil {
+		return err
+	}
+	return o.reviewOptions.resolve(cfg)
+}
+
+// publishOptions configures PublishFiles.
+type publishOptions struct {
+	gitOptions
+	commitOptions
+	reviewOptions
+	Source string
+	Dest   string
+	Prune  bool
+}
+
+func (o *publishOptions) register(fs *flag.FlagSet, cfg *Config) {
+	o.gitOptions.register(fs, cfg)
+	fs.StringVar(&o.Source, "src", cfg.Bundle.StagingDir, "local folder holding the generated files")
+	fs.StringVar(&o.Dest, "dest", ".", "folder in the repository the files are written to")
+	fs.BoolVar(&o.Prune, "prune", false, "delete files below -dest that are not in -src")
+	o.commitOptions.register(fs, cfg, "Update generated bundle content")
+	o.reviewOptions.register(fs, cfg)
+}
+
+func (o *publishOptions) resolve(cfg *Config) error {
+	if err := o.gitOptions.resolve(cfg); err != nil {
+		return err
+	}
+	if err := o.commitOptions.resolve(cfg); err != nil {
+		return err
+	}
+	return o.reviewOptions.resolve(cfg)
+}
+
+// bundleOptions configures BuildBundle.
+type bundleOptions struct {
+	Fetch       fetchOptions
+	Sources     []fetchOptions
+	DataFile    string
+	StagingDir  string
+	TarFile     string
+	PublishFile string
+}
+
+func (o *bundleOptions) register(fs *flag.FlagSet, cfg *Config) {
+	o.Fetch.register(fs, cfg)
+	fs.StringVar(&o.DataFile, "data", cfg.Bundle.DataFile, "JSON file to include as data.json")
+	fs.StringVar(&o.StagingDir, "staging-dir", cfg.Bundle.StagingDir, "folder below which the bundle content is staged in a temporary folder")
+	fs.StringVar(&o.TarFile, "out", cfg.Bundle.OutputFile, "path of the bundle tarball")
+	fs.StringVar(&o.PublishFile, "publish", cfg.Bundle.PublishFile, "path the tarball is copied to, empty to skip")
+}
+
+// resolve resolves the fetch flags and derives the options of every
+// configured source from them.
+func (o *bundleOptions) resolve(cfg *Config) error {
+	if err := o.Fetch.resolve(cfg); err != nil {
+		return err
+	}
+	for _, source := range cfg.Bundle.Sources {
+		s := o.Fetch
+		s.Name = source.Name
+		s.URL = source.URL
+		s.Ref = source.Ref
+		s.Path = source.Path
+		s.Root = source.Root
+		if source.Branch != "" {
+			s.Branch = source.Branch
+		}
+		if len(source.Include) > 0 {
+			s.Include = source.Include
+		}
+		if len(source.Exclude) > 0 {
+			s.Exclude = source.Exclude
+		}
+		if s.CacheDir != "" {
+			// Next to the main clone, whose worktree must not hold it
+			s.CacheDir = o.Fetch.CacheDir + "-" + source.Name
+		}
+		o.Sources = append(o.Sources, s)
+	}
+	return nil
+}
+
+// sources returns the repositories the policy files are taken from.
+func (o bundleOptions) sources() []fetchOptions {
+	if len(o.Sources) > 0 {
+		return o.Sources
+	}
+	return []fetchOptions{o.Fetch}
+}
+
+// watchOptions configures WatchBundle.
+type watchOptions struct {
+	Bundle   bundleOptions
+	Paths    []string
+	Interval time.Duration
+	Listen   string
+
+	secret credentials.Secret
+}
+
+func (o *watchOptions) register(fs *flag.FlagSet, cfg *Config) {
+	o.Bundle.register(fs, cfg)
+	o.Paths = cfg.Watch.Paths
+	fs.Var((*listFlag)(&o.Paths), "paths", "comma separated files and folders of the repo that trigger a rebuild, empty for all")
+	fs.DurationVar(&o.Interval, "interval", cfg.Watch.Interval, "how often the branch is polled, 0 to rely on the webhook")
+	fs.StringVar(&o.Listen, "listen", cfg.Watch.Listen, "address to serve the push webhook on, e.g. :8080, empty to only poll")
+}
+
+// resolve loads the optional webhook secret.
+func (o *watchOptions) resolve(cfg *Config) error {
+	if err := o.Bundle.Fetch.resolve(cfg); err != nil {
+		return err
+	}
+	if len(cfg.Bundle.Sources) > 0 {
+		return errors.New("bundle watch follows git.url only and does not support bundle.sources")
+	}
+	if o.Bundle.Fetch.Ref != "" {
+		return fmt.Errorf("-ref pins the bundle to %s, there is nothing to watch", o.Bundle.Fetch.Ref)
+	}
+	if o.Interval < 0 {
+		return fmt.Errorf("-interval must not be negative: %s", o.Interval)
+	}
+	if o.Interval == 0 && o.Listen == "" {
+		return errors.New("nothing would trigger a rebuild, set -interval or -listen")
+	}
+	if o.Listen == "" {
+		return nil
+	}
+
+	secret, err := cfg.SecretProvider().Secret(credentials.WebhookSecret)
+	if errors.Is(err, credentials.ErrNotFound) {
+		fmt.Fprintf(os.Stderr, "warning: no %s secret, webhook requests are not authenticated\n", credentials.WebhookSecret)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load webhook secret: %v", err)
+	}
+	o.secret = secret
+	return nil
+}
+
+// listFlag is a flag holding a comma separated list.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = nil
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// auditOptions configures AuditEntitlements.
+type auditOptions struct {
+	gitOptions
+	File   string
+	Filter edgeFilter
+}
+
+func (o *auditOptions) register(fs *flag.FlagSet, cfg *Config) {
+	o.gitOptions.register(fs, cfg)
+	fs.StringVar(&o.File, "file", "entitlements/resource-entitlements.yml", "path of the entitlements file in the repository")
+	fs.StringVar(&o.Filter.Group, "group", "", "only report edges of this LDAP group")
+	fs.StringVar(&o.Filter.Entitlement, "entitlement", "", "only report edges granting this entitlement")
+}
+
+// diffOptions configures DiffEntitlementRefs. FromFile and ToFile compare
+// local files instead of refs of the repository.
+type diffOptions struct {
+	gitOptions
+	File     string
+	From     string
+	To       string
+	FromFile string
+	ToFile   string
+	Format   string
+}
+
+func (o *diffOptions) register(fs *flag.FlagSet, cfg *Config) {
+	o.gitOptions.register(fs, cfg)
+	fs.StringVar(&o.File, "file", "entitlements/resource-entitlements.yml", "path of the entitlements file in the repository")
+	fs.StringVar(&o.From, "from", "", "tag, branch, commit or revision such as HEAD~1 to compare from")
+	fs.StringVar(&o.To, "to", "", "tag, branch, commit or revision to compare to, default the tip of -branch")
+	fs.StringVar(&o.FromFile, "from-file", "", "local entitlements file to compare from instead of -from")
+	fs.StringVar(&o.ToFile, "to-file", "", "local entitlements file to compare to instead of -to")
+	fs.StringVar(&o.Format, "format", formatText, "output format: text, json or markdown")
+}
+
+func (o *diffOptions) resolve(cfg *Config) error {
+	switch o.Format {
+	case formatText, formatJSON, formatMarkdown:
+	default:
+		return fmt.Errorf("unknown -format %q, use text, json or markdown", o.Format)
+	}
+	if o.From == "" && o.FromFile == "" {
+		return fmt.Errorf("one of -from or -from-file is required")
+	}
+	if o.From != "" && o.FromFile != "" {
+		return fmt.Errorf("-from and -from-file are mutually exclusive")
+	}
+	if o.To != "" && o.ToFile != "" {
+		return fmt.Errorf("-to and -to-file are mutually exclusive")
+	}
+	if o.FromFile != "" && o.ToFile != "" {
+		// Nothing is read from git
+		return nil
+	}
+	return o.gitOptions.resolve(cfg)
+}
+
+// userOptions configures UserEntitlements. Exactly one of User and
+// Account is set. The connection flags are only used with Live.
+type userOptions struct {
+	ldapOptions
+	File       string
+	UsersFile  string
+	GroupsFile string
+	User       string
+	Account    string
+	Live       bool
+}
+
+func (o *userOptions) register(fs *flag.FlagSet, cfg *Config) {
+	o.ldapOptions.register(fs, cfg)
+	fs.StringVar(&o.UserBaseDN, "user-base-dn", cfg.Ldap.UserBaseDN, "base DN of the user search")
+	fs.StringVar(&o.GroupBaseDN, "group-base-dn", cfg.Ldap.GroupBaseDN, "base DN of the group search")
+	fs.StringVar(&o.File, "file", "entitlements/resource-entitlements.yml", "entitlements file mapping LDAP groups to entitlements")
+	fs.StringVar(&o.UsersFile, "users", cfg.Snapshot.UsersFile, "user snapshot")
+	fs.StringVar(&o.GroupsFile, "groups", cfg.Snapshot.GroupsFile, "group snapshot")
+	fs.StringVar(&o.User, "user", "", "common name of the user")
+	fs.StringVar(&o.Account, "account", "", "sAMAccountName of the user")
+	fs.BoolVar(&o.Live, "live", false, "ask the directory instead of reading the snapshots")
+}
+
+func (o *userOptions) resolve(cfg *Config) error {
+	if (o.User == "") == (o.Account == "") {
+		return fmt.Errorf("exactly one of -user and -account is required")
+	}
+	if !o.Live {
+		return nil
+	}
+	return o.ldapOptions.resolve(cfg)
+}
+
+func runLdapUsers(cfg *Config, args []string) error {
+	var opts ldapOptions
+	fs := newFlagSet("ldap", "users")
+	opts.register(fs, cfg)
+	fs.StringVar(&opts.UserBaseDN, "base-dn", cfg.Ldap.UserBaseDN, "base DN of the search")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := opts.resolve(cfg); err != nil {
+		return err
+	}
+
+	out := jsonArray{w: os.Stdout}
+	err := opts.directory().EachUser(context.Background(), opts.Filter, func(u ldapsync.User) error {
+		return out.add(u)
+	})
+	if err != nil {
+		return err
+	}
+	return out.close()
+}
+
+func runLdapGroups(cfg *Config, args []string) error {
+	var opts ldapOptions
+	fs := newFlagSet("ldap", "groups")
+	opts.register(fs, cfg)
+	fs.StringVar(&opts.GroupBaseDN, "base-dn", cfg.Ldap.GroupBaseDN, "base DN of the search")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := opts.resolve(cfg); err != nil {
+		return err
+	}
+
+	out := jsonArray{w: os.Stdout}
+	err := opts.directory().EachGroup(context.Background(), opts.Filter, func(g ldapsync.Group) error {
+		return out.add(g)
+	})
+	if err != nil {
+		return err
+	}
+	return out.close()
+}
+
+// jsonArray writes a JSON array one element at a time, indented like the
+// snapshot files, so a large directory is not held in memory.
+type jsonArray struct {
+	w io.Writer
+	n int
+}
+
+func (a *jsonArray) add(v interface{}) error {
+	data, err := json.MarshalIndent(v, "\t", "\t")
+	if err != nil {
+		return err
+	}
+	sep := ",\n\t"
+	if a.n == 0 {
+		sep = "[\n\t"
+	}
+	a.n++
+	_, err = fmt.Fprintf(a.w, "%s%s", sep, data)
+	return err
+}
+
+func (a *jsonArray) close() error {
+	end := "\n]\n"
+	if a.n == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(a.w, end)
+	return err
+}
+
+func runLdapSync(cfg *Config, args []string) error {
+	var opts syncOptions
+	fs := newFlagSet("ldap", "sync")
+	opts.register(fs, cfg)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := opts.resolve(cfg); err != nil {
+		return err
+	}
+
+	return SyncSnapshots(opts)
+}
+
+func runLdapExport(cfg *Config, args []string) error {
+	var opts exportOptions
+	fs := newFlagSet("ldap", "export")
+	opts.register(fs, cfg)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := opts.ldapOptions.resolve(cfg); err != nil {
+		return err
+	}
+
+	return ExportSnapshots(opts)
+}
+
+func runLdapLookup(cfg *Config, args []string) error {
+	var opts lookupOptions
+	fs := newFlagSet("ldap", "lookup")
+	opts.register(fs, cfg)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := opts.resolve(); err != nil {
+		return err
+	}
+
+	return LookupSnapshot(opts, os.Stdout)
+}
+
+func runLdapCheck(cfg *Config, args []string) error {
+	var opts checkOptions
+	fs := newFlagSet("ldap", "check")
+	opts.register(fs, cfg)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	return CheckSnapshots(opts, os.Stdout)
+}
+
+func runGitFetch(cfg *Config, args []string) error {
+	var opts fetchOptions
+	fs := newFlagSet("git", "fetch")
+	opts.register(fs, cfg)
+	fs.StringVar(&opts.StagingDir, "staging-dir", cfg.Bundle.StagingDir, "folder the policy file is copied to")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := opts.resolve(cfg); err != nil {
+		return err
+	}
+
+	return FetchGitFile(opts)
+}
+
+func runGitUpdate(cfg *Config, args []string) error {
+	var opts updateOptions
+	fs := newFlagSet("git", "update")
+	opts.register(fs, cfg, " 13-----")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := opts.resolve(cfg); err != nil {
+		return err
+	}
+
+	return UpdateGitFile(opts)
+}
+
+func runGitTemp(cfg *Config, args []string) error {
+	var opts tempOptions
+	fs := newFlagSet("git", "temp")
+	opts.register(fs, cfg, "Hello world")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := opts.resolve(cfg); err != nil {
+		return err
+	}
+
+	_, _, err := makeTempRepo(opts)
+	return err
+}
+
+func runGitPublish(cfg *Config, args []string) error {
+	var opts publishOptions
+	fs := newFlagSet("git", "publish")
+	opts.register(fs, cfg)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := opts.resolve(cfg); err != nil {
+		return err
+	}
+
+	return PublishFiles(opts)
+}
+
+func runBundleBuild(cfg *Config, args []string) error {
+	var opts bundleOptions
+	fs := newFlagSet("bundle", "build")
+	opts.register(fs, cfg)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := opts.resolve(cfg); err != nil {
+		return err
+	}
+
+	return BuildBundle(opts)
+}
+
+func runBundleWatch(cfg *Config, args []string) error {
+	var opts watchOptions
+	fs := newFlagSet("bundle", "watch")
+	opts.register(fs, cfg)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := opts.resolve(cfg); err != nil {
+		return err
+	}
+
+	return WatchBundle(opts)
+}
+
+func runEntitlementsParse(cfg *Config, args []string) error {
+	fs := newFlagSet("entitlements", "parse")
+	file := fs.String("file", "entitlements/resource-entitlements.yml", "entitlements file to parse")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	config, err := ParseYMLFile(*file)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %v", *file, err)
+	}
+
+	fmt.Printf("Version: %s\n", config.Version)
+	for _, group := range config.LdapGroups {
+		var entitlements int
+		for _, role := range group.Roles {
+			for _, entGroup := range role.EntitlementGroups {
+				entitlements += len(entGroup.Entitlements)
+			}
+		}
+		fmt.Printf("LDAP Group: %s, roles: %d, entitlements: %d\n", group.Name, len(group.Roles), entitlements)
+	}
+	return nil
+}
+
+func runEntitlementsAudit(cfg *Config, args []string) error {
+	var opts auditOptions
+	fs := newFlagSet("entitlements", "audit")
+	opts.register(fs, cfg)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := opts.resolve(cfg); err != nil {
+		return err
+	}
+
+	return AuditEntitlements(opts, os.Stdout)
+}
+
+func runEntitlementsDiff(cfg *Config, args []string) error {
+	var opts diffOptions
+	fs := newFlagSet("entitlements", "diff")
+	opts.register(fs, cfg)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := opts.resolve(cfg); err != nil {
+		return err
+	}
+
+	return DiffEntitlementRefs(opts, os.Stdout)
+}
+
+func runEntitlementsUser(cfg *Config, args []string) error {
+	var opts userOptions
+	fs := newFlagSet("entitlements", "user")
+	opts.register(fs, cfg)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := opts.resolve(cfg); err != nil {
+		return err
+	}
+
+	return UserEntitlements(opts, os.Stdout)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+)
+
+func TestBundleSourcesInheritGlobs(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Bundle.Include = []string{"**/*.rego"}
+	cfg.Bundle.Exclude = []string{"**/*_test.rego"}
+	cfg.Bundle.Sources = []PolicySource{
+		{Name: "inherit", URL: "https://example.com/inherit.git"},
+		{Name: "own", URL: "https://example.com/own.git", Include: []string{"lib/*.rego"}, Exclude: []string{"lib/internal.rego"}},
+	}
+	var opts bundleOptions
+	fs := flag.NewFlagSet("bundle build", flag.ContinueOnError)
+	opts.register(fs, cfg)
+	if err := fs.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := opts.resolve(cfg); err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+
+	want := map[string][2][]string{
+		"inherit": {{"**/*.rego"}, {"**/*_test.rego"}},
+		"own":     {{"lib/*.rego"}, {"lib/internal.rego"}},
+	}
+	for _, s := range opts.Sources {
+		globs := [2][]string{s.Include, s.Exclude}
+		if !reflect.DeepEqual(globs, want[s.Name]) {
+			t.Errorf("source %s include %q exclude %q, want %q", s.Name, s.Include, s.Exclude, want[s.Name])
+		}
+	}
+}
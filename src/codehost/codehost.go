@@ -0,0 +1,22 @@
+// Package codehost opens pull requests on the service hosting the policy
+// repository, so generated changes can go through review instead of being
+// pushed straight to the release branch.
+package codehost
+
+import "context"
+
+// PullRequest describes a pull request to open.
+type PullRequest struct {
+	// Head is the branch holding the changes, already pushed to the remote.
+	Head string
+	// Base is the branch the changes are proposed for, e.g. release.
+	Base  string
+	Title string
+	Body  string
+}
+
+// Host opens pull requests on a code host.
+type Host interface {
+	// OpenPullRequest opens pr and returns the URL of the pull request.
+	OpenPullRequest(ctx context.Context, pr PullRequest) (string, error)
+}
@@ -0,0 +1,43 @@
+package codehost
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Fake is an in-process Host that records the pull requests it is asked to
+// open, for tests and dry runs.
+type Fake struct {
+	mu sync.Mutex
+	// Err, if set, is returned by OpenPullRequest instead of opening one.
+	// It is read with mu held, set it with SetErr once the Fake is in use.
+	Err   error
+	pulls []PullRequest
+}
+
+// OpenPullRequest implements Host. The returned URL is fake://pulls/<n>
+// where n counts the pull requests opened so far, starting at 1.
+func (f *Fake) OpenPullRequest(ctx context.Context, pr PullRequest) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return "", f.Err
+	}
+	f.pulls = append(f.pulls, pr)
+	return fmt.Sprintf("fake://pulls/%d", len(f.pulls)), nil
+}
+
+// SetErr sets Err, safely while pull requests are being opened.
+func (f *Fake) SetErr(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Err = err
+}
+
+// PullRequests returns the pull requests opened so far, oldest first.
+func (f *Fake) PullRequests() []PullRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]PullRequest(nil), f.pulls...)
+}
@@ -0,0 +1,30 @@
+package codehost
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestFakeSetErrWhileOpening(t *testing.T) {
+	f := &Fake{}
+	rejected := errors.New("rejected")
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f.OpenPullRequest(context.Background(), PullRequest{Head: "opa-publish/x", Base: "master"})
+		}()
+	}
+	f.SetErr(rejected)
+	wg.Wait()
+
+	if _, err := f.OpenPullRequest(context.Background(), PullRequest{}); err != rejected {
+		t.Errorf("OpenPullRequest error = %v, want %v", err, rejected)
+	}
+	if n := len(f.PullRequests()); n > 8 {
+		t.Errorf("opened %d pull requests, want at most 8", n)
+	}
+}
@@ -0,0 +1,127 @@
+package codehost
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/ashish246/GolangGitExample/src/credentials"
+)
+
+// DefaultGitHubAPI is the REST endpoint of github.com, used when
+// GitHub.APIURL is empty.
+const DefaultGitHubAPI = "https://api.github.com"
+
+// GitHub opens pull requests with the GitHub REST API.
+type GitHub struct {
+	// APIURL is the REST endpoint, e.g. https://github.example.com/api/v3
+	// for GitHub Enterprise. It defaults to DefaultGitHubAPI.
+	APIURL string
+	Owner  string
+	Repo   string
+	// Token is a personal access token allowed to open pull requests.
+	Token credentials.Secret
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// NewGitHub returns a GitHub host for repository, given as owner/name.
+func NewGitHub(repository string, token credentials.Secret) (*GitHub, error) {
+	parts := strings.Split(repository, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("repository %q is not of the form owner/name", repository)
+	}
+	return &GitHub{Owner: parts[0], Repo: parts[1], Token: token}, nil
+}
+
+// GitHubError is returned when the GitHub API rejects a request.
+type GitHubError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *GitHubError) Error() string {
+	return fmt.Sprintf("github: %d %s", e.StatusCode, e.Message)
+}
+
+// OpenPullRequest implements Host.
+func (g *GitHub) OpenPullRequest(ctx context.Context, pr PullRequest) (string, error) {
+	body, err := json.Marshal(map[string]string{
+		"head":  pr.Head,
+		"base":  pr.Base,
+		"title": pr.Title,
+		"body":  pr.Body,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	api := g.APIURL
+	if api == "" {
+		api = DefaultGitHubAPI
+	}
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls", strings.TrimSuffix(api, "/"), g.Owner, g.Repo)
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "token "+g.Token.Reveal())
+
+	client := g.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to open pull request for %s: %w", pr.Head, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read pull request response: %w", err)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return "", githubError(resp.StatusCode, data)
+	}
+
+	var created struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.Unmarshal(data, &created); err != nil {
+		return "", fmt.Errorf("failed to parse pull request response: %w", err)
+	}
+	if created.HTMLURL == "" {
+		return "", errors.New("github: pull request response has no html_url")
+	}
+	return created.HTMLURL, nil
+}
+
+// githubError builds a GitHubError from an error response, which carries a
+// message and, for validation failures, a list of errors.
+func githubError(status int, data []byte) error {
+	var resp struct {
+		Message string `json:"message"`
+		Errors  []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil || resp.Message == "" {
+		return &GitHubError{StatusCode: status, Message: http.StatusText(status)}
+	}
+	msg := resp.Message
+	for _, e := range resp.Errors {
+		if e.Message != "" {
+			msg += ": " + e.Message
+		}
+	}
+	return &GitHubError{StatusCode: status, Message: msg}
+}
@@ -0,0 +1,76 @@
+package codehost
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGitHubOpenPullRequest(t *testing.T) {
+	var got map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/ashish246/GolangGitExample/pulls" {
+			t.Errorf("request %s %s", r.Method, r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "token s3cret" {
+			t.Errorf("Authorization = %q", auth)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"number": 7, "html_url": "https://github.com/ashish246/GolangGitExample/pull/7"}`))
+	}))
+	defer server.Close()
+
+	g, err := NewGitHub("ashish246/GolangGitExample", "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.APIURL = server.URL + "/"
+	url, err := g.OpenPullRequest(context.Background(), PullRequest{
+		Head:  "opa-publish/0123456789ab",
+		Base:  "release",
+		Title: "Update entitlements",
+		Body:  "Generated from LDAP.",
+	})
+	if err != nil {
+		t.Fatalf("OpenPullRequest: %v", err)
+	}
+	if url != "https://github.com/ashish246/GolangGitExample/pull/7" {
+		t.Errorf("url = %q", url)
+	}
+	want := map[string]string{
+		"head":  "opa-publish/0123456789ab",
+		"base":  "release",
+		"title": "Update entitlements",
+		"body":  "Generated from LDAP.",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("request %s = %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func TestGitHubOpenPullRequestRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"message": "Validation Failed", "errors": [{"message": "A pull request already exists for ashish246:opa-publish/0123456789ab."}]}`))
+	}))
+	defer server.Close()
+
+	g := &GitHub{APIURL: server.URL, Owner: "ashish246", Repo: "GolangGitExample", Token: "s3cret"}
+	_, err := g.OpenPullRequest(context.Background(), PullRequest{Head: "opa-publish/0123456789ab", Base: "release"})
+	var ghErr *GitHubError
+	if !errors.As(err, &ghErr) {
+		t.Fatalf("OpenPullRequest error = %v, want a *GitHubError", err)
+	}
+	want := "Validation Failed: A pull request already exists for ashish246:opa-publish/0123456789ab."
+	if ghErr.StatusCode != http.StatusUnprocessableEntity || ghErr.Message != want {
+		t.Errorf("error = %d %q, want 422 %q", ghErr.StatusCode, ghErr.Message, want)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"text/template"
+	"time"
+
+	"github.com/ashish246/GolangGitExample/src/credentials"
+	"github.com/ashish246/GolangGitExample/src/gitstore"
+	"golang.org/x/crypto/openpgp"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// defaultMessageTemplate is used when commit.message_template is not set.
+const defaultMessageTemplate = `{{.Subject}}
+{{if .EntitlementVersion}}
+Entitlements version: {{.EntitlementVersion}}{{end}}{{if .SnapshotTime}}
+LDAP snapshot: {{.SnapshotTime}}{{end}}
+Branch: {{.Branch}}
+Changes: {{.Changes}}{{range .Changes.Added}}
+  added:    {{.}}{{end}}{{range .Changes.Modified}}
+  modified: {{.}}{{end}}{{range .Changes.Deleted}}
+  deleted:  {{.}}{{end}}
+`
+
+// commitMessage is the data commit.message_template is rendered with.
+type commitMessage struct {
+	// Subject is the -message flag of the subcommand
+	Subject string
+	Branch  string
+	// SnapshotTime is the lastmodified time of the LDAP snapshot given with
+	// -ldap-snapshot, empty when none was given
+	SnapshotTime string
+	// EntitlementVersion is the version of the entitlements file given with
+	// -entitlements, empty when none was given
+	EntitlementVersion string
+	Changes            gitstore.Summary
+}
+
+// commitOptions holds the settings of the commits made by the git
+// subcommands.
+type commitOptions struct {
+	Message          string
+	AuthorName       string
+	AuthorEmail      string
+	SnapshotFile     string
+	EntitlementsFile string
+	Sign             bool
+
+	committerName  string
+	committerEmail string
+	template       *template.Template
+	signKey        *openpgp.Entity
+}
+
+// identity fills in a blank name or email from the standard git variables
+// nameVar and emailVar, then from the OS account running the tool.
+func identity(name, email, nameVar, emailVar string) (string, string) {
+	if name == "" {
+		name = os.Getenv(nameVar)
+	}
+	if email == "" {
+		email = os.Getenv(emailVar)
+	}
+	if name != "" && email != "" {
+		return name, email
+	}
+
+	account := "opa-git"
+	if u, err := user.Current(); err == nil {
+		account = u.Username
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "localhost"
+	}
+	if name == "" {
+		name = account
+	}
+	if email == "" {
+		email = account + "@" + host
+	}
+	return name, email
+}
+
+func (o *commitOptions) register(fs *flag.FlagSet, cfg *Config, message string) {
+	name, email := identity(cfg.Commit.AuthorName, cfg.Commit.AuthorEmail, "GIT_AUTHOR_NAME", "GIT_AUTHOR_EMAIL")
+
+	fs.StringVar(&o.Message, "message", message, "commit subject, available to the message template as .Subject")
+	fs.StringVar(&o.AuthorName, "author-name", name, "commit author name")
+	fs.StringVar(&o.AuthorEmail, "author-email", email, "commit author email")
+	fs.StringVar(&o.SnapshotFile, "ldap-snapshot", "", "LDAP snapshot whose lastmodified time is recorded in the message")
+	fs.StringVar(&o.EntitlementsFile, "entitlements", "", "entitlements file whose version is recorded in the message")
+	fs.BoolVar(&o.Sign, "sign", cfg.Commit.Sign, "sign the commit with the gpg_signing_key secret")
+}
+
+// resolve loads the committer identity, the message template and, when
+// signing, the signing key.
+func (o *commitOptions) resolve(cfg *Config) error {
+	o.committerName, o.committerEmail = identity(cfg.Commit.CommitterName, cfg.Commit.CommitterEmail, "GIT_COMMITTER_NAME", "GIT_COMMITTER_EMAIL")
+
+	tmpl, err := cfg.messageTemplate()
+	if err != nil {
+		return err
+	}
+	o.template = tmpl
+
+	if o.Sign {
+		key, err := credentials.SigningKey(cfg.SecretProvider())
+		if err != nil {
+			return fmt.Errorf("failed to load commit signing key: %v", err)
+		}
+		o.signKey = key
+	}
+	return nil
+}
+
+// commit returns the gitstore options for a commit on branch.
+func (o commitOptions) commit(branch string) (gitstore.CommitOptions, error) {
+	data := commitMessage{Subject: o.Message, Branch: branch}
+
+	if o.SnapshotFile != "" {
+		var snapshot struct {
+			LastModified time.Time `json:"lastmodified"`
+		}
+		content, err := ioutil.ReadFile(o.SnapshotFile)
+		if err != nil {
+			return gitstore.CommitOptions{}, err
+		}
+		if err := json.Unmarshal(content, &snapshot); err != nil {
+			return gitstore.CommitOptions{}, fmt.Errorf("failed to parse LDAP snapshot %s: %v", o.SnapshotFile, err)
+		}
+		data.SnapshotTime = snapshot.LastModified.UTC().Format(time.RFC3339)
+	}
+	if o.EntitlementsFile != "" {
+		entitlements, err := ParseYMLFile(o.EntitlementsFile)
+		if err != nil {
+			return gitstore.CommitOptions{}, fmt.Errorf("failed to parse entitlements %s: %v", o.EntitlementsFile, err)
+		}
+		data.EntitlementVersion = entitlements.Version
+	}
+
+	now := time.Now()
+	return gitstore.CommitOptions{
+		Author:    &object.Signature{Name: o.AuthorName, Email: o.AuthorEmail, When: now},
+		Committer: &object.Signature{Name: o.committerName, Email: o.committerEmail, When: now},
+		SignKey:   o.signKey,
+		Message: func(changes gitstore.Summary) (string, error) {
+			data.Changes = changes
+			var buf bytes.Buffer
+			if err := o.template.Execute(&buf, data); err != nil {
+				return "", err
+			}
+			return buf.String(), nil
+		},
+	}, nil
+}
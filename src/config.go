@@ -0,0 +1,609 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ashish246/GolangGitExample/src/codehost"
+	"github.com/ashish246/GolangGitExample/src/credentials"
+	"github.com/ashish246/GolangGitExample/src/gitstore"
+	"github.com/ashish246/GolangGitExample/src/ldapsync"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/yaml.v2"
+)
+
+// envPrefix is prepended to the upper-cased config key to form the name of
+// the environment variable overriding it, e.g. git.url -> OPA_GIT_URL.
+const envPrefix = "OPA_"
+
+// secretEnvPrefix is prepended to the upper-cased secret name when secrets
+// are read from the environment, e.g. git_password -> OPA_SECRET_GIT_PASSWORD.
+const secretEnvPrefix = "OPA_SECRET_"
+
+// Git auth methods accepted by git.auth.
+const (
+	gitAuthNone  = "none"
+	gitAuthBasic = "basic"
+	gitAuthToken = "token"
+	gitAuthSSH   = "ssh"
+)
+
+// Credential providers accepted by credentials.provider.
+const (
+	providerEnv  = "env"
+	providerFile = "file"
+)
+
+// Publish modes accepted by publish.mode.
+const (
+	publishPush        = "push"
+	publishPullRequest = "pull_request"
+)
+
+// Code hosts accepted by codehost.provider.
+const (
+	codeHostGitHub = "github"
+	codeHostFake   = "fake"
+)
+
+// scpURL matches the scp-like syntax git accepts for ssh remotes,
+// e.g. git@github.com:ashish246/GolangGitExample.git.
+var scpURL = regexp.MustCompile(`^[\w.-]+@[\w.-]+:[^/]`)
+
+// Config holds every environment specific value used by the subcommands.
+// It is loaded from a YAML file and then overridden from the environment.
+type Config struct {
+	Git struct {
+		URL      string `yaml:"url"`
+		Branch   string `yaml:"branch"`
+		Username string `yaml:"username"`
+		Auth     string `yaml:"auth"`
+		// PushAttempts and PushBackoff bound the retries of a push
+		// rejected because another writer pushed first.
+		PushAttempts int           `yaml:"push_attempts"`
+		PushBackoff  time.Duration `yaml:"push_backoff"`
+		// VerifyKeyRing is an armored OpenPGP public keyring. When set the
+		// HEAD commit must carry a signature made by one of its keys before
+		// a bundle is built from it.
+		VerifyKeyRing string `yaml:"verify_keyring"`
+		// CacheDir keeps a clone on disk that is fetched between runs
+		// instead of cloning into memory every time.
+		CacheDir string `yaml:"cache_dir"`
+		// Depth limits the history cloned into memory; it must be 0 with
+		// CacheDir.
+		Depth        int  `yaml:"depth"`
+		SingleBranch bool `yaml:"single_branch"`
+	} `yaml:"git"`
+	Ldap struct {
+		// URL is the ldap:// or ldaps:// URL of the directory. When set it
+		// replaces host and port.
+		URL  string `yaml:"url"`
+		Host string `yaml:"host"`
+		Port int    `yaml:"port"`
+		// StartTLS upgrades an ldap:// connection to TLS before binding.
+		StartTLS bool `yaml:"start_tls"`
+		// CAFile, ServerName, ClientCertFile and ClientKeyFile configure
+		// the TLS connection, see ldapsync.TLSOptions.
+		CAFile         string `yaml:"ca_file"`
+		ServerName     string `yaml:"server_name"`
+		ClientCertFile string `yaml:"client_cert_file"`
+		ClientKeyFile  string `yaml:"client_key_file"`
+		// AllowInsecureBind permits sending the bind password over a
+		// connection without TLS.
+		AllowInsecureBind bool `yaml:"allow_insecure_bind"`
+		// PageSize is the number of entries requested per search page, -1
+		// to search without paging.
+		PageSize    int    `yaml:"page_size"`
+		BindDN      string `yaml:"bind_dn"`
+		UserBaseDN  string `yaml:"user_base_dn"`
+		GroupBaseDN string `yaml:"group_base_dn"`
+		Filter      string `yaml:"filter"`
+		// NestedGroups is how the groups of nested groups are resolved:
+		// auto, in_chain or walk, see ldapsync.Options.
+		NestedGroups string `yaml:"nested_groups"`
+	} `yaml:"ldap"`
+	Bundle struct {
+		// Ref is the tag, branch or commit hash the policy files are
+		// taken from, the tip of git.branch when empty.
+		Ref string `yaml:"ref"`
+		// Include and Exclude are the globs selecting the policy files
+		// copied from the repo into the bundle, see gitstore.Glob.
+		Include []string `yaml:"include"`
+		Exclude []string `yaml:"exclude"`
+		// Sources, when set, replace git.url, bundle.ref, include and
+		// exclude as the repositories the policy files are taken from.
+		// They cannot be overridden from the environment.
+		Sources     []PolicySource `yaml:"sources"`
+		DataFile    string         `yaml:"data_file"`
+		StagingDir  string         `yaml:"staging_dir"`
+		OutputFile  string         `yaml:"output_file"`
+		PublishFile string         `yaml:"publish_file"`
+	} `yaml:"bundle"`
+	Credentials struct {
+		Provider string `yaml:"provider"`
+		Dir      string `yaml:"dir"`
+	} `yaml:"credentials"`
+	// Commit identities default to the GIT_AUTHOR_* and GIT_COMMITTER_*
+	// variables, then to the OS account running the tool.
+	Commit struct {
+		AuthorName      string `yaml:"author_name"`
+		AuthorEmail     string `yaml:"author_email"`
+		CommitterName   string `yaml:"committer_name"`
+		CommitterEmail  string `yaml:"committer_email"`
+		MessageTemplate string `yaml:"message_template"`
+		// Sign signs commits with the gpg_signing_key secret.
+		Sign bool `yaml:"sign"`
+	} `yaml:"commit"`
+	Publish struct {
+		// Mode is push to push commits straight to git.branch, or
+		// pull_request to push them to a generated branch and open a pull
+		// request against git.branch.
+		Mode         string `yaml:"mode"`
+		BranchPrefix string `yaml:"branch_prefix"`
+	} `yaml:"publish"`
+	// Snapshot locates the ldap-users.json and ldap-groups.json snapshots
+	// of the directory maintained by ldap sync.
+	Snapshot struct {
+		UsersFile  string `yaml:"users_file"`
+		GroupsFile string `yaml:"groups_file"`
+		// StateFile records when the last full sync ran.
+		StateFile string `yaml:"state_file"`
+		// FullSyncInterval is how often ldap sync reads the whole
+		// directory to detect deletions instead of only the entries
+		// modified since the last sync.
+		FullSyncInterval time.Duration `yaml:"full_sync_interval"`
+	} `yaml:"snapshot"`
+	Watch struct {
+		// Paths are the files and folders of the repo whose changes
+		// trigger a rebuild, all of them when empty.
+		Paths    []string      `yaml:"paths"`
+		Interval time.Duration `yaml:"interval"`
+		// Listen is the address the push webhook is served on, empty to
+		// only poll.
+		Listen string `yaml:"listen"`
+	} `yaml:"watch"`
+	CodeHost struct {
+		Provider string `yaml:"provider"`
+		APIURL   string `yaml:"api_url"`
+		// Repository is the owner/name of the policy repo on the host.
+		Repository string `yaml:"repository"`
+	} `yaml:"codehost"`
+}
+
+// PolicySource is a repository the bundle's policy files are taken from.
+type PolicySource struct {
+	// Name identifies the source in messages and in the bundle revision.
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+	// Branch defaults to git.branch.
+	Branch string `yaml:"branch"`
+	Ref    string `yaml:"ref"`
+	// Path is the folder of the repo the files are taken from and Root
+	// the folder of the bundle they are copied to; both default to the
+	// top level.
+	Path string `yaml:"path"`
+	Root string `yaml:"root"`
+	// Include and Exclude default to bundle.include and bundle.exclude;
+	// all are relative to Path.
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+}
+
+// ConfigError reports an invalid value for a configuration key.
+type ConfigError struct {
+	Key    string
+	Reason string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("config key %s: %s", e.Key, e.Reason)
+}
+
+// DefaultConfig returns the configuration used when no file is given.
+func DefaultConfig() *Config {
+	c := &Config{}
+	c.Git.URL = "https://github.com/ashish246/GolangGitExample.git"
+	c.Git.Branch = "release"
+	c.Git.Auth = gitAuthNone
+	c.Git.PushAttempts = 5
+	c.Git.PushBackoff = time.Second
+	c.Ldap.Host = "localhost"
+	c.Ldap.Port = 389
+	c.Ldap.PageSize = ldapsync.DefaultPageSize
+	c.Ldap.BindDN = "cn=admin,dc=globaltest,dc=anz,dc=com"
+	c.Ldap.UserBaseDN = "cn=CAZ05,ou=Users,ou=AU,dc=globaltest,dc=anz,dc=com"
+	c.Ldap.GroupBaseDN = "cn=AU Digital BD Read,ou=Groups,ou=AU,dc=globaltest,dc=anz,dc=com"
+	c.Ldap.Filter = ldapsync.DefaultFilter
+	c.Ldap.NestedGroups = ldapsync.NestedAuto
+	c.Bundle.Include = []string{"opa-policy.rego"}
+	c.Bundle.DataFile = "opa-bundle-sample.json"
+	c.Bundle.StagingDir = "tempOpa"
+	c.Bundle.OutputFile = "../opa-bundling-service/opabundles/bundle-opapoc.tar.gz"
+	c.Bundle.PublishFile = "../opa-bundling-service/nginx/html/opapoc/bundle-opapoc.tar.gz"
+	c.Credentials.Provider = providerEnv
+	c.Commit.MessageTemplate = defaultMessageTemplate
+	c.Publish.Mode = publishPush
+	c.Publish.BranchPrefix = "opa-publish/"
+	c.CodeHost.Provider = codeHostGitHub
+	c.CodeHost.APIURL = codehost.DefaultGitHubAPI
+	c.Watch.Interval = time.Minute
+	c.Snapshot.UsersFile = "ldap-users.json"
+	c.Snapshot.GroupsFile = "ldap-groups.json"
+	c.Snapshot.StateFile = "ldap-sync-state.json"
+	c.Snapshot.FullSyncInterval = 24 * time.Hour
+	return c
+}
+
+// LoadConfig reads the YAML file at path over the defaults, applies the
+// environment overrides and validates the result. An empty path skips the
+// file and only applies the environment.
+func LoadConfig(path string) (*Config, error) {
+	c := DefaultConfig()
+
+	if path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %v", err)
+		}
+		if err := yaml.UnmarshalStrict(data, c); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %v", path, err)
+		}
+	}
+
+	if err := c.applyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// configField is a single configuration value addressed by its dotted key.
+// Value is a *string, an *int, a *bool, a *time.Duration or a *[]string,
+// which is overridden from a comma separated list.
+type configField struct {
+	Key   string
+	Value interface{}
+}
+
+func (c *Config) fields() []configField {
+	return []configField{
+		{"git.url", &c.Git.URL},
+		{"git.branch", &c.Git.Branch},
+		{"git.username", &c.Git.Username},
+		{"git.auth", &c.Git.Auth},
+		{"git.push_attempts", &c.Git.PushAttempts},
+		{"git.push_backoff", &c.Git.PushBackoff},
+		{"git.verify_keyring", &c.Git.VerifyKeyRing},
+		{"git.cache_dir", &c.Git.CacheDir},
+		{"git.depth", &c.Git.Depth},
+		{"git.single_branch", &c.Git.SingleBranch},
+		{"ldap.url", &c.Ldap.URL},
+		{"ldap.host", &c.Ldap.Host},
+		{"ldap.port", &c.Ldap.Port},
+		{"ldap.start_tls", &c.Ldap.StartTLS},
+		{"ldap.ca_file", &c.Ldap.CAFile},
+		{"ldap.server_name", &c.Ldap.ServerName},
+		{"ldap.client_cert_file", &c.Ldap.ClientCertFile},
+		{"ldap.client_key_file", &c.Ldap.ClientKeyFile},
+		{"ldap.allow_insecure_bind", &c.Ldap.AllowInsecureBind},
+		{"ldap.page_size", &c.Ldap.PageSize},
+		{"ldap.bind_dn", &c.Ldap.BindDN},
+		{"ldap.user_base_dn", &c.Ldap.UserBaseDN},
+		{"ldap.group_base_dn", &c.Ldap.GroupBaseDN},
+		{"ldap.filter", &c.Ldap.Filter},
+		{"ldap.nested_groups", &c.Ldap.NestedGroups},
+		{"bundle.ref", &c.Bundle.Ref},
+		{"bundle.include", &c.Bundle.Include},
+		{"bundle.exclude", &c.Bundle.Exclude},
+		{"bundle.data_file", &c.Bundle.DataFile},
+		{"bundle.staging_dir", &c.Bundle.StagingDir},
+		{"bundle.output_file", &c.Bundle.OutputFile},
+		{"bundle.publish_file", &c.Bundle.PublishFile},
+		{"credentials.provider", &c.Credentials.Provider},
+		{"credentials.dir", &c.Credentials.Dir},
+		{"commit.author_name", &c.Commit.AuthorName},
+		{"commit.author_email", &c.Commit.AuthorEmail},
+		{"commit.committer_name", &c.Commit.CommitterName},
+		{"commit.committer_email", &c.Commit.CommitterEmail},
+		{"commit.message_template", &c.Commit.MessageTemplate},
+		{"commit.sign", &c.Commit.Sign},
+		{"publish.mode", &c.Publish.Mode},
+		{"publish.branch_prefix", &c.Publish.BranchPrefix},
+		{"snapshot.users_file", &c.Snapshot.UsersFile},
+		{"snapshot.groups_file", &c.Snapshot.GroupsFile},
+		{"snapshot.state_file", &c.Snapshot.StateFile},
+		{"snapshot.full_sync_interval", &c.Snapshot.FullSyncInterval},
+		{"watch.paths", &c.Watch.Paths},
+		{"watch.interval", &c.Watch.Interval},
+		{"watch.listen", &c.Watch.Listen},
+		{"codehost.provider", &c.CodeHost.Provider},
+		{"codehost.api_url", &c.CodeHost.APIURL},
+		{"codehost.repository", &c.CodeHost.Repository},
+	}
+}
+
+// envName returns the environment variable overriding key.
+func envName(key string) string {
+	return envPrefix + strings.ToUpper(strings.Replace(key, ".", "_", -1))
+}
+
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	for _, field := range c.fields() {
+		value, ok := lookup(envName(field.Key))
+		if !ok {
+			continue
+		}
+		switch v := field.Value.(type) {
+		case *string:
+			*v = value
+		case *int:
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return &ConfigError{Key: field.Key, Reason: fmt.Sprintf("%s is not a number: %q", envName(field.Key), value)}
+			}
+			*v = n
+		case *bool:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return &ConfigError{Key: field.Key, Reason: fmt.Sprintf("%s is not a boolean: %q", envName(field.Key), value)}
+			}
+			*v = b
+		case *time.Duration:
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return &ConfigError{Key: field.Key, Reason: fmt.Sprintf("%s is not a duration: %q", envName(field.Key), value)}
+			}
+			*v = d
+		case *[]string:
+			(*listFlag)(v).Set(value)
+		}
+	}
+	return nil
+}
+
+// Validate checks that the required keys are set and well formed.
+func (c *Config) Validate() error {
+	required := map[string]string{
+		"git.url":                 c.Git.URL,
+		"git.branch":              c.Git.Branch,
+		"ldap.bind_dn":            c.Ldap.BindDN,
+		"bundle.staging_dir":      c.Bundle.StagingDir,
+		"bundle.output_file":      c.Bundle.OutputFile,
+		"commit.message_template": c.Commit.MessageTemplate,
+	}
+	// Walk the fields so errors are reported in a stable order.
+	for _, field := range c.fields() {
+		if value, ok := required[field.Key]; ok && strings.TrimSpace(value) == "" {
+			return &ConfigError{Key: field.Key, Reason: "must not be empty"}
+		}
+	}
+
+	if err := validateGitURL(c.Git.URL); err != nil {
+		return err
+	}
+	switch c.Git.Auth {
+	case gitAuthNone, gitAuthBasic, gitAuthToken, gitAuthSSH:
+	default:
+		return &ConfigError{Key: "git.auth", Reason: fmt.Sprintf("unknown auth method %q", c.Git.Auth)}
+	}
+	if c.Git.PushAttempts < 1 {
+		return &ConfigError{Key: "git.push_attempts", Reason: fmt.Sprintf("must be at least 1: %d", c.Git.PushAttempts)}
+	}
+	if c.Git.PushBackoff < 0 {
+		return &ConfigError{Key: "git.push_backoff", Reason: fmt.Sprintf("must not be negative: %s", c.Git.PushBackoff)}
+	}
+	if c.Git.Depth < 0 {
+		return &ConfigError{Key: "git.depth", Reason: fmt.Sprintf("must not be negative: %d", c.Git.Depth)}
+	}
+	if c.Git.Depth > 0 && c.Git.CacheDir != "" {
+		return &ConfigError{Key: "git.depth", Reason: "must be 0 with git.cache_dir, a shallow cache cannot be fetched into"}
+	}
+	if len(c.Bundle.Include) == 0 {
+		return &ConfigError{Key: "bundle.include", Reason: "must not be empty"}
+	}
+	for _, pattern := range c.Bundle.Include {
+		if err := gitstore.ValidatePattern(pattern); err != nil {
+			return &ConfigError{Key: "bundle.include", Reason: err.Error()}
+		}
+	}
+	for _, pattern := range c.Bundle.Exclude {
+		if err := gitstore.ValidatePattern(pattern); err != nil {
+			return &ConfigError{Key: "bundle.exclude", Reason: err.Error()}
+		}
+	}
+	names := map[string]bool{}
+	for i, source := range c.Bundle.Sources {
+		key := fmt.Sprintf("bundle.sources[%d]", i)
+		if source.Name == "" {
+			return &ConfigError{Key: key + ".name", Reason: "must not be empty"}
+		}
+		if names[source.Name] {
+			return &ConfigError{Key: key + ".name", Reason: fmt.Sprintf("duplicate source %q", source.Name)}
+		}
+		names[source.Name] = true
+		var urlErr *ConfigError
+		if err := validateGitURL(source.URL); errors.As(err, &urlErr) {
+			return &ConfigError{Key: key + ".url", Reason: urlErr.Reason}
+		}
+		if !relativeDir(source.Path) {
+			return &ConfigError{Key: key + ".path", Reason: fmt.Sprintf("must be a folder inside the repo: %q", source.Path)}
+		}
+		if !relativeDir(source.Root) {
+			return &ConfigError{Key: key + ".root", Reason: fmt.Sprintf("must be a folder inside the bundle: %q", source.Root)}
+		}
+		for _, pattern := range append(append([]string(nil), source.Include...), source.Exclude...) {
+			if err := gitstore.ValidatePattern(pattern); err != nil {
+				return &ConfigError{Key: key, Reason: err.Error()}
+			}
+		}
+	}
+	if c.Snapshot.FullSyncInterval < 0 {
+		return &ConfigError{Key: "snapshot.full_sync_interval", Reason: fmt.Sprintf("must not be negative: %s", c.Snapshot.FullSyncInterval)}
+	}
+	if c.Watch.Interval < 0 {
+		return &ConfigError{Key: "watch.interval", Reason: fmt.Sprintf("must not be negative: %s", c.Watch.Interval)}
+	}
+	if err := c.validateLdap(); err != nil {
+		return err
+	}
+	switch c.Credentials.Provider {
+	case providerEnv:
+	case providerFile:
+		if c.Credentials.Dir == "" {
+			return &ConfigError{Key: "credentials.dir", Reason: "must be set for the file provider"}
+		}
+	default:
+		return &ConfigError{Key: "credentials.provider", Reason: fmt.Sprintf("unknown provider %q", c.Credentials.Provider)}
+	}
+	if _, err := c.messageTemplate(); err != nil {
+		return err
+	}
+	switch c.Publish.Mode {
+	case publishPush:
+	case publishPullRequest:
+		if c.Publish.BranchPrefix == "" {
+			return &ConfigError{Key: "publish.branch_prefix", Reason: "must be set for pull requests"}
+		}
+	default:
+		return &ConfigError{Key: "publish.mode", Reason: fmt.Sprintf("unknown mode %q", c.Publish.Mode)}
+	}
+	switch c.CodeHost.Provider {
+	case codeHostGitHub:
+		if c.Publish.Mode != publishPullRequest {
+			break
+		}
+		if u, err := url.Parse(c.CodeHost.APIURL); err != nil || !u.IsAbs() {
+			return &ConfigError{Key: "codehost.api_url", Reason: fmt.Sprintf("not an absolute URL: %q", c.CodeHost.APIURL)}
+		}
+		if parts := strings.Split(c.CodeHost.Repository, "/"); len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return &ConfigError{Key: "codehost.repository", Reason: fmt.Sprintf("must be owner/name: %q", c.CodeHost.Repository)}
+		}
+	case codeHostFake:
+	default:
+		return &ConfigError{Key: "codehost.provider", Reason: fmt.Sprintf("unknown code host %q", c.CodeHost.Provider)}
+	}
+	return nil
+}
+
+// messageTemplate parses commit.message_template.
+func (c *Config) messageTemplate() (*template.Template, error) {
+	tmpl, err := template.New("commit").Option("missingkey=error").Parse(c.Commit.MessageTemplate)
+	if err != nil {
+		return nil, &ConfigError{Key: "commit.message_template", Reason: err.Error()}
+	}
+	return tmpl, nil
+}
+
+// relativeDir reports whether dir stays below the folder it is relative to.
+func relativeDir(dir string) bool {
+	clean := path.Clean(dir)
+	return !path.IsAbs(clean) && clean != ".." && !strings.HasPrefix(clean, "../")
+}
+
+// validateGitURL accepts absolute URLs and scp-like ssh remotes, and rejects
+// URLs carrying a password since those end up in the remote config.
+func validateGitURL(rawurl string) error {
+	if scpURL.MatchString(rawurl) {
+		return nil
+	}
+	// Only echo the redacted URL, it may hold the very password rejected below.
+	u, err := url.Parse(rawurl)
+	if err != nil || u.Scheme == "" || (u.Host == "" && u.Scheme != "file") {
+		return &ConfigError{Key: "git.url", Reason: fmt.Sprintf("not an absolute URL: %q", credentials.RedactURL(rawurl))}
+	}
+	if _, ok := u.User.Password(); ok {
+		return &ConfigError{Key: "git.url", Reason: "must not embed a password, use git.auth and the credentials provider"}
+	}
+	return nil
+}
+
+// validateLdap checks the address and TLS settings of the directory.
+func (c *Config) validateLdap() error {
+	if c.Ldap.URL == "" {
+		if strings.TrimSpace(c.Ldap.Host) == "" {
+			return &ConfigError{Key: "ldap.host", Reason: "must not be empty when ldap.url is not set"}
+		}
+		if c.Ldap.Port < 1 || c.Ldap.Port > 65535 {
+			return &ConfigError{Key: "ldap.port", Reason: fmt.Sprintf("out of range: %d", c.Ldap.Port)}
+		}
+	} else {
+		u, err := url.Parse(c.Ldap.URL)
+		if err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") || u.Hostname() == "" {
+			return &ConfigError{Key: "ldap.url", Reason: fmt.Sprintf("not an ldap:// or ldaps:// URL: %q", c.Ldap.URL)}
+		}
+		if u.Scheme == "ldaps" && c.Ldap.StartTLS {
+			return &ConfigError{Key: "ldap.start_tls", Reason: "cannot be used with an ldaps URL"}
+		}
+	}
+	if c.Ldap.PageSize == 0 || c.Ldap.PageSize < -1 {
+		return &ConfigError{Key: "ldap.page_size", Reason: fmt.Sprintf("must be positive or -1 to disable paging: %d", c.Ldap.PageSize)}
+	}
+	if (c.Ldap.ClientCertFile == "") != (c.Ldap.ClientKeyFile == "") {
+		return &ConfigError{Key: "ldap.client_cert_file", Reason: "must be set together with ldap.client_key_file"}
+	}
+	switch c.Ldap.NestedGroups {
+	case ldapsync.NestedAuto, ldapsync.NestedInChain, ldapsync.NestedWalk:
+	default:
+		return &ConfigError{Key: "ldap.nested_groups", Reason: fmt.Sprintf("must be auto, in_chain or walk: %q", c.Ldap.NestedGroups)}
+	}
+	return nil
+}
+
+// SecretProvider returns the provider selected by credentials.provider.
+func (c *Config) SecretProvider() credentials.Provider {
+	if c.Credentials.Provider == providerFile {
+		return credentials.FileProvider{Dir: c.Credentials.Dir}
+	}
+	return credentials.EnvProvider{Prefix: secretEnvPrefix}
+}
+
+// PullRequestHost returns the code host selected by codehost.provider.
+func (c *Config) PullRequestHost() (codehost.Host, error) {
+	if c.CodeHost.Provider == codeHostFake {
+		return &codehost.Fake{}, nil
+	}
+	token, err := c.SecretProvider().Secret(credentials.GitHubToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load GitHub token: %v", err)
+	}
+	host, err := codehost.NewGitHub(c.CodeHost.Repository, token)
+	if err != nil {
+		return nil, err
+	}
+	host.APIURL = c.CodeHost.APIURL
+	return host, nil
+}
+
+// GitAuth returns the auth method selected by git.auth for username, or nil
+// when the repository is accessed anonymously.
+func (c *Config) GitAuth(username string) (transport.AuthMethod, error) {
+	var (
+		auth transport.AuthMethod
+		err  error
+	)
+	switch c.Git.Auth {
+	case gitAuthBasic:
+		auth, err = credentials.BasicAuth(c.SecretProvider(), username)
+	case gitAuthToken:
+		auth, err = credentials.TokenAuth(c.SecretProvider())
+	case gitAuthSSH:
+		auth, err = credentials.SSHKeyAuth(c.SecretProvider(), username)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s git credentials: %v", c.Git.Auth, err)
+	}
+	return auth, nil
+}
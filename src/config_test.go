@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestLoadConfigExample(t *testing.T) {
+	c, err := LoadConfig("../config.example.yml")
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if c.Commit.MessageTemplate == DefaultConfig().Commit.MessageTemplate {
+		t.Errorf("commit.message_template was not read from the example")
+	}
+	if c.CodeHost.Repository == "" {
+		t.Errorf("codehost.repository was not read from the example")
+	}
+}
+
+func TestValidateShallowCache(t *testing.T) {
+	c, err := LoadConfig("../config.example.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Git.CacheDir = "/var/cache/opa/policy-repo"
+	c.Git.Depth = 1
+	err = c.Validate()
+	if e, ok := err.(*ConfigError); !ok || e.Key != "git.depth" {
+		t.Errorf("Validate = %v, want a git.depth error", err)
+	}
+}
@@ -0,0 +1,130 @@
+// Package credentials supplies the secrets used to authenticate against the
+// policy repository and the directory without putting them in config files,
+// command lines or remote URLs.
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ErrNotFound is returned by a Provider that has no secret with the
+// requested name.
+var ErrNotFound = errors.New("secret not found")
+
+// Secret is a sensitive value. It redacts itself when formatted so it can
+// be passed around without ending up in logs or error strings; call Reveal
+// at the point the raw value is handed to a client library.
+type Secret string
+
+const redacted = "[REDACTED]"
+
+func (s Secret) String() string {
+	return redacted
+}
+
+// GoString keeps %#v from printing the value.
+func (s Secret) GoString() string {
+	return redacted
+}
+
+// Reveal returns the raw secret value.
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+// Provider looks secrets up by name, e.g. "git_password".
+type Provider interface {
+	Secret(name string) (Secret, error)
+}
+
+// EnvProvider reads secrets from environment variables named after the
+// upper-cased secret name with Prefix prepended, e.g. OPA_SECRET_GIT_TOKEN.
+type EnvProvider struct {
+	Prefix string
+}
+
+func (p EnvProvider) Secret(name string) (Secret, error) {
+	key := p.Prefix + strings.ToUpper(name)
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return "", fmt.Errorf("%w: environment variable %s is not set", ErrNotFound, key)
+	}
+	return Secret(value), nil
+}
+
+// FileProvider reads secrets from a directory holding one file per secret,
+// which is how Kubernetes and Docker mount secrets. Surrounding whitespace
+// is trimmed so files written with a trailing newline work.
+type FileProvider struct {
+	Dir string
+}
+
+func (p FileProvider) Secret(name string) (Secret, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid secret name %q", name)
+	}
+
+	path := filepath.Join(p.Dir, name)
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("%w: %s does not exist", ErrNotFound, path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret: %v", err)
+	}
+	return Secret(strings.TrimSpace(string(data))), nil
+}
+
+func isNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// userinfo matches the credentials part of a URL embedded in free text.
+// Passwords may themselves contain '@', so match up to the last one before
+// the path.
+var userinfo = regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9+.-]*://)[^/\s]+@`)
+
+// RedactURL removes the user information from rawurl so it can be printed.
+func RedactURL(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil || u.User == nil {
+		return userinfo.ReplaceAllString(rawurl, "${1}")
+	}
+	u.User = nil
+	return u.String()
+}
+
+// Redact removes the user information of any URL in s, e.g. in an error
+// message returned by a transport.
+func Redact(s string) string {
+	return userinfo.ReplaceAllString(s, "${1}")
+}
+
+// RedactError returns err with the user information of any URL in its
+// message removed, see Redact. The result unwraps to err so errors.Is and
+// errors.As still see the original error.
+func RedactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &redactedError{err: err}
+}
+
+type redactedError struct {
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return Redact(e.err.Error())
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,73 @@
+package credentials
+
+import (
+	"fmt"
+
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
+)
+
+// Names of the secrets looked up by the git auth methods and the
+// subcommands.
+const (
+	GitPassword      = "git_password"
+	GitHubToken      = "github_token"
+	SSHPrivateKey    = "ssh_private_key"
+	SSHKeyPassphrase = "ssh_key_passphrase"
+	LdapBindPassword = "ldap_bind_password"
+	WebhookSecret    = "webhook_secret"
+)
+
+// tokenUsername is sent with GitHub personal access tokens. GitHub ignores
+// the username when the password is a token but go-git requires one.
+const tokenUsername = "x-access-token"
+
+// BasicAuth returns HTTP basic auth for username with the password read
+// from the GitPassword secret.
+func BasicAuth(p Provider, username string) (transport.AuthMethod, error) {
+	password, err := p.Secret(GitPassword)
+	if err != nil {
+		return nil, err
+	}
+	return &http.BasicAuth{
+		Username: username,
+		Password: password.Reveal(),
+	}, nil
+}
+
+// TokenAuth returns HTTP auth using the GitHub personal access token read
+// from the GitHubToken secret.
+func TokenAuth(p Provider) (transport.AuthMethod, error) {
+	token, err := p.Secret(GitHubToken)
+	if err != nil {
+		return nil, err
+	}
+	return &http.BasicAuth{
+		Username: tokenUsername,
+		Password: token.Reveal(),
+	}, nil
+}
+
+// SSHKeyAuth returns public key auth for go-git's ssh transport. The PEM
+// encoded private key is read from the SSHPrivateKey secret and decrypted
+// with the optional SSHKeyPassphrase secret. user defaults to "git".
+func SSHKeyAuth(p Provider, user string) (transport.AuthMethod, error) {
+	key, err := p.Secret(SSHPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	passphrase, err := p.Secret(SSHKeyPassphrase)
+	if err != nil && !isNotFound(err) {
+		return nil, err
+	}
+	if user == "" {
+		user = ssh.DefaultUsername
+	}
+
+	auth, err := ssh.NewPublicKeys(user, []byte(key.Reveal()), passphrase.Reveal())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ssh private key %s: %v", SSHPrivateKey, err)
+	}
+	return auth, nil
+}
@@ -0,0 +1,76 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/openpgp"
+)
+
+// Names of the secrets looked up by SigningKey.
+const (
+	GPGSigningKey    = "gpg_signing_key"
+	GPGKeyPassphrase = "gpg_key_passphrase"
+)
+
+// SigningKey returns the OpenPGP entity used to sign commits. The armored
+// private key is read from the GPGSigningKey secret and, when encrypted,
+// decrypted with the GPGKeyPassphrase secret.
+func SigningKey(p Provider) (*openpgp.Entity, error) {
+	key, err := p.Secret(GPGSigningKey)
+	if err != nil {
+		return nil, err
+	}
+	entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(key.Reveal()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", GPGSigningKey, err)
+	}
+	if len(entities) != 1 {
+		return nil, fmt.Errorf("%s must hold exactly one key, found %d", GPGSigningKey, len(entities))
+	}
+	entity := entities[0]
+	if entity.PrivateKey == nil {
+		return nil, fmt.Errorf("%s holds a public key, a private key is needed to sign", GPGSigningKey)
+	}
+
+	if !isEncrypted(entity) {
+		return entity, nil
+	}
+	passphrase, err := p.Secret(GPGKeyPassphrase)
+	if err != nil {
+		return nil, fmt.Errorf("%s is encrypted: %v", GPGSigningKey, err)
+	}
+	if err := decrypt(entity, []byte(passphrase.Reveal())); err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s: %v", GPGSigningKey, err)
+	}
+	return entity, nil
+}
+
+func isEncrypted(entity *openpgp.Entity) bool {
+	if entity.PrivateKey.Encrypted {
+		return true
+	}
+	for _, subkey := range entity.Subkeys {
+		if subkey.PrivateKey != nil && subkey.PrivateKey.Encrypted {
+			return true
+		}
+	}
+	return false
+}
+
+func decrypt(entity *openpgp.Entity, passphrase []byte) error {
+	if entity.PrivateKey.Encrypted {
+		if err := entity.PrivateKey.Decrypt(passphrase); err != nil {
+			return errors.New("wrong passphrase")
+		}
+	}
+	for _, subkey := range entity.Subkeys {
+		if subkey.PrivateKey != nil && subkey.PrivateKey.Encrypted {
+			if err := subkey.PrivateKey.Decrypt(passphrase); err != nil {
+				return errors.New("wrong passphrase")
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ashish246/GolangGitExample/src/gitstore"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/yaml.v2"
+)
+
+// Output formats of an EntitlementDiff.
+const (
+	formatText     = "text"
+	formatJSON     = "json"
+	formatMarkdown = "markdown"
+)
+
+// EntitlementDiff is the semantic difference between two revisions of an
+// entitlements file. The order of groups, roles and entitlements in the
+// files is ignored.
+type EntitlementDiff struct {
+	From          string               `json:"from"`
+	To            string               `json:"to"`
+	GroupsAdded   []string             `json:"groups_added"`
+	GroupsRemoved []string             `json:"groups_removed"`
+	Roles         []RoleChanges        `json:"roles"`
+	Entitlements  []EntitlementChanges `json:"entitlements"`
+}
+
+// RoleChanges lists the roles added to and removed from an LDAP group.
+type RoleChanges struct {
+	Group   string   `json:"ldap_group"`
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+// EntitlementChanges lists the entitlements added to and removed from an
+// entitlement group of a role of an LDAP group. Groups and roles that were
+// added or removed as a whole list all their entitlements.
+type EntitlementChanges struct {
+	Group            string   `json:"ldap_group"`
+	Role             string   `json:"role"`
+	EntitlementGroup string   `json:"entitlement_group"`
+	Added            []string `json:"added"`
+	Removed          []string `json:"removed"`
+}
+
+// DiffEntitlementRefs writes the differences between the entitlements file
+// at two refs of the configured repository, or between two local files, to
+// w in opts.Format.
+func DiffEntitlementRefs(opts diffOptions, w io.Writer) error {
+	var repo *gitstore.PolicyRepo
+	if opts.FromFile == "" || opts.ToFile == "" {
+		// Keep the clone progress out of the diff
+		store := opts.storeOptions()
+		store.Progress = nil
+		var err error
+		if repo, err = gitstore.Open(store); err != nil {
+			return err
+		}
+	}
+
+	load := func(file, ref string) (string, LdapGroupEntitlements, error) {
+		if file != "" {
+			c, err := ParseYMLFile(file)
+			if err != nil {
+				return "", LdapGroupEntitlements{}, fmt.Errorf("failed to parse %s: %v", file, err)
+			}
+			return file, c, nil
+		}
+		if ref == "" {
+			ref = repo.Branch()
+		}
+		commit, err := repo.Resolve(ref)
+		if err != nil {
+			return "", LdapGroupEntitlements{}, err
+		}
+		c, err := entitlementsAt(repo, commit, opts.File)
+		return ref, c, err
+	}
+
+	fromLabel, from, err := load(opts.FromFile, opts.From)
+	if err != nil {
+		return err
+	}
+	toLabel, to, err := load(opts.ToFile, opts.To)
+	if err != nil {
+		return err
+	}
+	return DiffEntitlements(fromLabel, from, toLabel, to).Write(w, opts.Format)
+}
+
+// entitlementsAt parses the entitlements file name in the tree of commit. A
+// missing file grants nothing, so adding or deleting it diffs as every
+// group being added or removed.
+func entitlementsAt(repo *gitstore.PolicyRepo, commit *object.Commit, name string) (LdapGroupEntitlements, error) {
+	var c LdapGroupEntitlements
+	content, err := repo.ReadFileAt(commit, name)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	if err := yaml.Unmarshal(content, &c); err != nil {
+		return c, fmt.Errorf("failed to parse %s at %s: %v", name, commit.Hash, err)
+	}
+	return c, nil
+}
+
+// entitlementTree is an entitlements file as LDAP group -> role ->
+// entitlement group -> set of entitlements.
+type entitlementTree map[string]roleTree
+
+// roleTree maps the roles of an LDAP group to their entitlement groups.
+type roleTree map[string]entitlementGroupTree
+
+// entitlementGroupTree maps the entitlement groups of a role to their
+// entitlements.
+type entitlementGroupTree map[string]entitlementSet
+
+type entitlementSet map[string]bool
+
+func newEntitlementTree(c LdapGroupEntitlements) entitlementTree {
+	tree := entitlementTree{}
+	for _, group := range c.LdapGroups {
+		if tree[group.Name] == nil {
+			tree[group.Name] = roleTree{}
+		}
+		for _, role := range group.Roles {
+			roles := tree[group.Name]
+			if roles[role.Name] == nil {
+				roles[role.Name] = entitlementGroupTree{}
+			}
+			for _, entGroup := range role.EntitlementGroups {
+				if roles[role.Name][entGroup.Name] == nil {
+					roles[role.Name][entGroup.Name] = entitlementSet{}
+				}
+				for _, entitlement := range entGroup.Entitlements {
+					roles[role.Name][entGroup.Name][entitlement] = true
+				}
+			}
+		}
+	}
+	return tree
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// DiffEntitlements compares two parsed entitlements files. from and to
+// label the revisions in the output.
+func DiffEntitlements(fromLabel string, from LdapGroupEntitlements, toLabel string, to LdapGroupEntitlements) EntitlementDiff {
+	old, cur := newEntitlementTree(from), newEntitlementTree(to)
+	d := EntitlementDiff{
+		From:          fromLabel,
+		To:            toLabel,
+		GroupsAdded:   []string{},
+		GroupsRemoved: []string{},
+		Roles:         []RoleChanges{},
+		Entitlements:  []EntitlementChanges{},
+	}
+	d.GroupsAdded, d.GroupsRemoved = diffKeys(sortedKeys(old), sortedKeys(cur))
+
+	for _, group := range unionKeys(sortedKeys(old), sortedKeys(cur)) {
+		if old[group] != nil && cur[group] != nil {
+			added, removed := diffKeys(sortedKeys(old[group]), sortedKeys(cur[group]))
+			if len(added) > 0 || len(removed) > 0 {
+				d.Roles = append(d.Roles, RoleChanges{Group: group, Added: added, Removed: removed})
+			}
+		}
+		for _, role := range unionKeys(sortedKeys(old[group]), sortedKeys(cur[group])) {
+			for _, entGroup := range unionKeys(sortedKeys(old[group][role]), sortedKeys(cur[group][role])) {
+				added, removed := diffKeys(sortedKeys(old[group][role][entGroup]), sortedKeys(cur[group][role][entGroup]))
+				if len(added) > 0 || len(removed) > 0 {
+					d.Entitlements = append(d.Entitlements, EntitlementChanges{
+						Group:            group,
+						Role:             role,
+						EntitlementGroup: entGroup,
+						Added:            added,
+						Removed:          removed,
+					})
+				}
+			}
+		}
+	}
+	return d
+}
+
+// diffKeys returns the sorted keys only in cur as added and only in old as
+// removed.
+func diffKeys(old, cur []string) (added, removed []string) {
+	oldKeys, curKeys := keySet(old), keySet(cur)
+	added, removed = []string{}, []string{}
+	for _, k := range cur {
+		if !oldKeys[k] {
+			added = append(added, k)
+		}
+	}
+	for _, k := range old {
+		if !curKeys[k] {
+			removed = append(removed, k)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+// unionKeys returns the sorted keys in a or b.
+func unionKeys(a, b []string) []string {
+	keys := keySet(a)
+	union := append([]string(nil), a...)
+	for _, k := range b {
+		if !keys[k] {
+			union = append(union, k)
+		}
+	}
+	sort.Strings(union)
+	return union
+}
+
+func keySet(keys []string) map[string]bool {
+	set := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		set[k] = true
+	}
+	return set
+}
+
+// Empty reports whether both revisions grant the same entitlements.
+func (d EntitlementDiff) Empty() bool {
+	return len(d.GroupsAdded) == 0 && len(d.GroupsRemoved) == 0 && len(d.Roles) == 0 && len(d.Entitlements) == 0
+}
+
+// Write renders d in format, one of text, json or markdown.
+func (d EntitlementDiff) Write(w io.Writer, format string) error {
+	switch format {
+	case formatText:
+		_, err := io.WriteString(w, d.Text())
+		return err
+	case formatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(d)
+	case formatMarkdown:
+		_, err := io.WriteString(w, d.Markdown())
+		return err
+	}
+	return fmt.Errorf("unknown format %q, use text, json or markdown", format)
+}
+
+// Text renders d for a terminal.
+func (d EntitlementDiff) Text() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "Entitlement changes from %s to %s\n", d.From, d.To)
+	if d.Empty() {
+		b.WriteString("No changes\n")
+		return b.String()
+	}
+	list := func(title string, added, removed []string) {
+		fmt.Fprintf(&b, "\n%s\n", title)
+		for _, name := range added {
+			fmt.Fprintf(&b, "  + %s\n", name)
+		}
+		for _, name := range removed {
+			fmt.Fprintf(&b, "  - %s\n", name)
+		}
+	}
+	if len(d.GroupsAdded) > 0 || len(d.GroupsRemoved) > 0 {
+		list("LDAP groups:", d.GroupsAdded, d.GroupsRemoved)
+	}
+	for _, r := range d.Roles {
+		list(fmt.Sprintf("Roles of %s:", r.Group), r.Added, r.Removed)
+	}
+	for _, e := range d.Entitlements {
+		list(fmt.Sprintf("Entitlements of %s -> %s -> %s:", e.Group, e.Role, e.EntitlementGroup), e.Added, e.Removed)
+	}
+	return b.String()
+}
+
+// Markdown renders d for a pull request description.
+func (d EntitlementDiff) Markdown() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "### Entitlement changes (%s → %s)\n\n", code(d.From), code(d.To))
+	if d.Empty() {
+		b.WriteString("No changes.\n")
+		return b.String()
+	}
+	if len(d.GroupsAdded) > 0 {
+		fmt.Fprintf(&b, "**LDAP groups added:** %s\n\n", codeList(d.GroupsAdded))
+	}
+	if len(d.GroupsRemoved) > 0 {
+		fmt.Fprintf(&b, "**LDAP groups removed:** %s\n\n", codeList(d.GroupsRemoved))
+	}
+	if len(d.Roles) > 0 {
+		b.WriteString("#### Roles\n\n| LDAP group | Added | Removed |\n|---|---|---|\n")
+		for _, r := range d.Roles {
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", cell(r.Group), codeList(r.Added), codeList(r.Removed))
+		}
+		b.WriteString("\n")
+	}
+	if len(d.Entitlements) > 0 {
+		b.WriteString("#### Entitlements\n\n| LDAP group | Role | Entitlement group | Added | Removed |\n|---|---|---|---|---|\n")
+		for _, e := range d.Entitlements {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n", cell(e.Group), cell(e.Role), cell(e.EntitlementGroup),
+				strings.Join(codeEach(e.Added), "<br>"), strings.Join(codeEach(e.Removed), "<br>"))
+		}
+	}
+	return b.String()
+}
+
+// cell escapes the table separator in a markdown table cell.
+func cell(s string) string {
+	return strings.Replace(s, "|", `\|`, -1)
+}
+
+func code(s string) string {
+	return "`" + cell(s) + "`"
+}
+
+func codeEach(names []string) []string {
+	coded := make([]string, len(names))
+	for i, name := range names {
+		coded[i] = code(name)
+	}
+	return coded
+}
+
+func codeList(names []string) string {
+	return strings.Join(codeEach(names), ", ")
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func parseEntitlements(t *testing.T, content string) LdapGroupEntitlements {
+	var c LdapGroupEntitlements
+	if err := yaml.Unmarshal([]byte(content), &c); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+const diffBase = `version: '1.0'
+ldap_groups:
+    - name: AU Digital CSP Support
+      roles:
+        - name: support
+          entitlement_groups:
+            - name: party
+              entitlements:
+                - party.read
+                - party.write
+    - name: AU Digital BD Read
+      roles:
+        - name: reader
+          entitlement_groups:
+            - name: bd
+              entitlements:
+                - bd.read
+`
+
+func TestDiffEntitlements(t *testing.T) {
+	tests := []struct {
+		name          string
+		to            string
+		groupsAdded   []string
+		groupsRemoved []string
+		roles         []RoleChanges
+		entitlements  []EntitlementChanges
+	}{
+		{
+			name: "reordered",
+			to: `version: '1.0'
+ldap_groups:
+    - name: AU Digital BD Read
+      roles:
+        - name: reader
+          entitlement_groups:
+            - name: bd
+              entitlements:
+                - bd.read
+    - name: AU Digital CSP Support
+      roles:
+        - name: support
+          entitlement_groups:
+            - name: party
+              entitlements:
+                - party.write
+                - party.read
+`,
+		},
+		{
+			name: "entitlement added and removed",
+			to: `version: '1.0'
+ldap_groups:
+    - name: AU Digital CSP Support
+      roles:
+        - name: support
+          entitlement_groups:
+            - name: party
+              entitlements:
+                - party.read
+                - party.delete
+    - name: AU Digital BD Read
+      roles:
+        - name: reader
+          entitlement_groups:
+            - name: bd
+              entitlements:
+                - bd.read
+`,
+			entitlements: []EntitlementChanges{
+				{Group: "AU Digital CSP Support", Role: "support", EntitlementGroup: "party", Added: []string{"party.delete"}, Removed: []string{"party.write"}},
+			},
+		},
+		{
+			name: "group and role replaced",
+			to: `version: '1.0'
+ldap_groups:
+    - name: AU Digital CSP Support
+      roles:
+        - name: admin
+          entitlement_groups:
+            - name: party
+              entitlements:
+                - party.read
+    - name: AU Digital DAZ Read
+      roles:
+        - name: reader
+          entitlement_groups:
+            - name: daz
+              entitlements:
+                - daz.read
+`,
+			groupsAdded:   []string{"AU Digital DAZ Read"},
+			groupsRemoved: []string{"AU Digital BD Read"},
+			roles: []RoleChanges{
+				{Group: "AU Digital CSP Support", Added: []string{"admin"}, Removed: []string{"support"}},
+			},
+			entitlements: []EntitlementChanges{
+				{Group: "AU Digital BD Read", Role: "reader", EntitlementGroup: "bd", Added: []string{}, Removed: []string{"bd.read"}},
+				{Group: "AU Digital CSP Support", Role: "admin", EntitlementGroup: "party", Added: []string{"party.read"}, Removed: []string{}},
+				{Group: "AU Digital CSP Support", Role: "support", EntitlementGroup: "party", Added: []string{}, Removed: []string{"party.read", "party.write"}},
+				{Group: "AU Digital DAZ Read", Role: "reader", EntitlementGroup: "daz", Added: []string{"daz.read"}, Removed: []string{}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := DiffEntitlements("a", parseEntitlements(t, diffBase), "b", parseEntitlements(t, tt.to))
+			want := EntitlementDiff{
+				From:          "a",
+				To:            "b",
+				GroupsAdded:   tt.groupsAdded,
+				GroupsRemoved: tt.groupsRemoved,
+				Roles:         tt.roles,
+				Entitlements:  tt.entitlements,
+			}
+			if want.GroupsAdded == nil {
+				want.GroupsAdded = []string{}
+			}
+			if want.GroupsRemoved == nil {
+				want.GroupsRemoved = []string{}
+			}
+			if want.Roles == nil {
+				want.Roles = []RoleChanges{}
+			}
+			if want.Entitlements == nil {
+				want.Entitlements = []EntitlementChanges{}
+			}
+			if !reflect.DeepEqual(d, want) {
+				t.Errorf("DiffEntitlements =\n%+v\nwant\n%+v", d, want)
+			}
+			if d.Empty() != (tt.name == "reordered") {
+				t.Errorf("Empty() = %v", d.Empty())
+			}
+		})
+	}
+}
+
+func TestDiffEntitlementsEmptyFile(t *testing.T) {
+	d := DiffEntitlements("(none)", LdapGroupEntitlements{}, "b", parseEntitlements(t, diffBase))
+	want := []string{"AU Digital BD Read", "AU Digital CSP Support"}
+	if !reflect.DeepEqual(d.GroupsAdded, want) || len(d.GroupsRemoved) != 0 {
+		t.Errorf("groups added %q removed %q, want %q added", d.GroupsAdded, d.GroupsRemoved, want)
+	}
+	if len(d.Entitlements) != 2 {
+		t.Errorf("%d entitlement changes, want one per entitlement group", len(d.Entitlements))
+	}
+}
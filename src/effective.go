@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/ashish246/GolangGitExample/src/ldapsync"
+	"gopkg.in/ldap.v3"
+)
+
+// UserEntitlements prints the entitlements granted to the LDAP groups of
+// the entitlements file the user belongs to, directly or as a member of a
+// group nested in them. Entitlements inherited from a parent group name
+// the groups they come through.
+func UserEntitlements(opts userOptions, w io.Writer) error {
+	config, err := ParseYMLFile(opts.File)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %v", opts.File, err)
+	}
+
+	var user ldapsync.User
+	var memberships []ldapsync.Membership
+	if opts.Live {
+		user, memberships, err = liveMemberships(opts)
+	} else {
+		user, memberships, err = snapshotMemberships(opts)
+	}
+	if err != nil {
+		return err
+	}
+
+	groups := map[string]bool{}
+	through := map[string][]string{}
+	for _, m := range memberships {
+		groups[m.Group.CommonName] = true
+		through[m.Group.CommonName] = m.Via
+	}
+	fmt.Fprintf(w, "User %s (%s)\n", user.CommonName, user.SAMAccountName)
+	edges := config.EdgesOf(groups)
+	if len(edges) == 0 {
+		fmt.Fprintf(w, "  no entitlements in %s\n", opts.File)
+	}
+	for _, e := range edges {
+		fmt.Fprintf(w, "  %s%s\n", e, via(through[e.Group]))
+	}
+	return nil
+}
+
+// snapshotMemberships finds the user and their groups in the snapshots.
+func snapshotMemberships(opts userOptions) (ldapsync.User, []ldapsync.Membership, error) {
+	index, err := ldapsync.LoadIndex(opts.UsersFile, opts.GroupsFile)
+	if err != nil {
+		return ldapsync.User{}, nil, err
+	}
+	var user ldapsync.User
+	var ok bool
+	if opts.User != "" {
+		user, ok = index.User(opts.User)
+	} else {
+		user, ok = index.UserBySAMAccountName(opts.Account)
+	}
+	if !ok {
+		return ldapsync.User{}, nil, fmt.Errorf("no user %s%s in %s", opts.User, opts.Account, opts.UsersFile)
+	}
+	return user, index.NestedGroupsOf(user.CommonName), nil
+}
+
+// liveMemberships searches the directory for the user and their groups.
+// The directory does not tell which groups a nested membership goes
+// through, so Via is always empty.
+func liveMemberships(opts userOptions) (ldapsync.User, []ldapsync.Membership, error) {
+	ctx := context.Background()
+	dir := opts.directory()
+	filter := "(cn=" + ldap.EscapeFilter(opts.User) + ")"
+	if opts.Account != "" {
+		filter = "(sAMAccountName=" + ldap.EscapeFilter(opts.Account) + ")"
+	}
+	users, err := dir.Users(ctx, filter)
+	if err != nil {
+		return ldapsync.User{}, nil, err
+	}
+	if len(users) != 1 {
+		return ldapsync.User{}, nil, fmt.Errorf("%d users match %s below %s", len(users), filter, opts.UserBaseDN)
+	}
+	groups, err := dir.NestedGroupsOf(ctx, users[0].EntryDN)
+	if err != nil {
+		return ldapsync.User{}, nil, err
+	}
+	memberships := make([]ldapsync.Membership, len(groups))
+	for i, g := range groups {
+		memberships[i] = ldapsync.Membership{Group: g}
+	}
+	return users[0], memberships, nil
+}
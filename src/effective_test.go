@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ashish246/GolangGitExample/src/ldapsync"
+)
+
+const nestedEntitlements = `version: '1.0'
+ldap_groups:
+    - name: AU Digital CSP Support
+      roles:
+        - name: support
+          entitlement_groups:
+            - name: support
+              entitlements:
+                - read
+                - write
+    - name: AU Digital BDS Users
+      roles:
+        - name: bds
+          entitlement_groups:
+            - name: bds
+              entitlements:
+                - read
+`
+
+func TestUserEntitlementsThroughParentGroup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "effective")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// alice is only in CSP Tier 1, which is a member of CSP Support
+	users := &ldapsync.UserSnapshot{Users: map[string]ldapsync.User{
+		"alice": {CommonName: "alice", SAMAccountName: "alice1", MemberOf: []string{"CSP Tier 1"}},
+	}}
+	groups := &ldapsync.GroupSnapshot{Groups: map[string]ldapsync.Group{
+		"CSP Tier 1":             {CommonName: "CSP Tier 1", Member: []string{"alice"}},
+		"AU Digital CSP Support": {CommonName: "AU Digital CSP Support", Member: []string{"CSP Tier 1"}},
+		"AU Digital BDS Users":   {CommonName: "AU Digital BDS Users", Member: []string{"bob"}},
+	}}
+	opts := userOptions{
+		File:       filepath.Join(dir, "entitlements.yml"),
+		UsersFile:  filepath.Join(dir, "ldap-users.json"),
+		GroupsFile: filepath.Join(dir, "ldap-groups.json"),
+		Account:    "ALICE1",
+	}
+	if err := users.Write(opts.UsersFile); err != nil {
+		t.Fatal(err)
+	}
+	if err := groups.Write(opts.GroupsFile); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(opts.File, []byte(nestedEntitlements), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := UserEntitlements(opts, &out); err != nil {
+		t.Fatalf("UserEntitlements: %v", err)
+	}
+	want := "User alice (alice1)\n" +
+		"  AU Digital CSP Support -> support -> support -> read (via CSP Tier 1)\n" +
+		"  AU Digital CSP Support -> support -> support -> write (via CSP Tier 1)\n"
+	if out.String() != want {
+		t.Errorf("printed\n%s\nwant\n%s", out.String(), want)
+	}
+
+	opts.Account = "nobody"
+	if err := UserEntitlements(opts, &bytes.Buffer{}); err == nil {
+		t.Error("UserEntitlements of an unknown user succeeded")
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Edge is one grant of an entitlement to an LDAP group, through a role and
+// an entitlement group, in an entitlements file.
+type Edge struct {
+	Group            string `json:"ldap_group"`
+	Role             string `json:"role"`
+	EntitlementGroup string `json:"entitlement_group"`
+	Entitlement      string `json:"entitlement"`
+}
+
+func (e Edge) String() string {
+	return strings.Join([]string{e.Group, e.Role, e.EntitlementGroup, e.Entitlement}, " -> ")
+}
+
+// less orders edges by group, then role, entitlement group and entitlement.
+func (e Edge) less(o Edge) bool {
+	if e.Group != o.Group {
+		return e.Group < o.Group
+	}
+	if e.Role != o.Role {
+		return e.Role < o.Role
+	}
+	if e.EntitlementGroup != o.EntitlementGroup {
+		return e.EntitlementGroup < o.EntitlementGroup
+	}
+	return e.Entitlement < o.Entitlement
+}
+
+// Edges returns the set of group -> role -> entitlement group ->
+// entitlement edges of the file.
+func (c LdapGroupEntitlements) Edges() map[Edge]bool {
+	edges := map[Edge]bool{}
+	for _, group := range c.LdapGroups {
+		for _, role := range group.Roles {
+			for _, entGroup := range role.EntitlementGroups {
+				for _, entitlement := range entGroup.Entitlements {
+					edges[Edge{group.Name, role.Name, entGroup.Name, entitlement}] = true
+				}
+			}
+		}
+	}
+	return edges
+}
+
+// EdgesOf returns the edges granted to the LDAP groups, sorted.
+func (c LdapGroupEntitlements) EdgesOf(groups map[string]bool) []Edge {
+	var edges []Edge
+	for e := range c.Edges() {
+		if groups[e.Group] {
+			edges = append(edges, e)
+		}
+	}
+	sortEdges(edges)
+	return edges
+}
+
+// diffEdges returns the edges only in to as added and the edges only in
+// from as removed, both sorted.
+func diffEdges(from, to map[Edge]bool) (added, removed []Edge) {
+	for e := range to {
+		if !from[e] {
+			added = append(added, e)
+		}
+	}
+	for e := range from {
+		if !to[e] {
+			removed = append(removed, e)
+		}
+	}
+	sortEdges(added)
+	sortEdges(removed)
+	return added, removed
+}
+
+func sortEdges(edges []Edge) {
+	sort.Slice(edges, func(i, j int) bool { return edges[i].less(edges[j]) })
+}
+
+// edgeFilter keeps the edges of one LDAP group and/or one entitlement; an
+// empty field matches everything.
+type edgeFilter struct {
+	Group       string
+	Entitlement string
+}
+
+func (f edgeFilter) apply(edges []Edge) []Edge {
+	var kept []Edge
+	for _, e := range edges {
+		if (f.Group == "" || e.Group == f.Group) && (f.Entitlement == "" || e.Entitlement == f.Entitlement) {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+func (f edgeFilter) String() string {
+	var parts []string
+	if f.Group != "" {
+		parts = append(parts, fmt.Sprintf("group %q", f.Group))
+	}
+	if f.Entitlement != "" {
+		parts = append(parts, fmt.Sprintf("entitlement %q", f.Entitlement))
+	}
+	return strings.Join(parts, " and ")
+}
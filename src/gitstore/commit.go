@@ -0,0 +1,130 @@
+package gitstore
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"golang.org/x/crypto/openpgp"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// Summary lists the paths changed by a commit, each in lexical order.
+type Summary struct {
+	Added    []string
+	Modified []string
+	Deleted  []string
+}
+
+func (s Summary) String() string {
+	return fmt.Sprintf("%d added, %d modified, %d deleted", len(s.Added), len(s.Modified), len(s.Deleted))
+}
+
+// Empty reports whether nothing changed.
+func (s Summary) Empty() bool {
+	return len(s.Added)+len(s.Modified)+len(s.Deleted) == 0
+}
+
+// MessageFunc returns the commit message for the staged changes.
+type MessageFunc func(changes Summary) (string, error)
+
+// Message returns a MessageFunc that always returns msg.
+func Message(msg string) MessageFunc {
+	return func(Summary) (string, error) {
+		return msg, nil
+	}
+}
+
+// CommitOptions describes the commits created by Commit, CommitFiles and
+// Update.
+type CommitOptions struct {
+	Author *object.Signature
+	// Committer defaults to Author.
+	Committer *object.Signature
+	Message   MessageFunc
+	// SignKey signs the commit with OpenPGP when set.
+	SignKey *openpgp.Entity
+}
+
+// CommitFiles applies files as WriteFiles does and records all of them in a
+// single commit. When the result is identical to HEAD nothing is committed
+// and the returned commit is nil.
+func (p *PolicyRepo) CommitFiles(files map[string][]byte, opts CommitOptions) (*object.Commit, error) {
+	if err := p.WriteFiles(files); err != nil {
+		return nil, err
+	}
+
+	status, err := p.worktree.Status()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree status: %w", err)
+	}
+	if status.IsClean() || !hasStagedChanges(status) {
+		return nil, nil
+	}
+	return p.commit(status, opts)
+}
+
+// hasStagedChanges reports whether the index differs from HEAD. Files left
+// untracked in the worktree, e.g. after Reset, make the status unclean but
+// would not change the commit.
+func hasStagedChanges(status git.Status) bool {
+	for _, file := range status {
+		if file.Staging != git.Unmodified && file.Staging != git.Untracked {
+			return true
+		}
+	}
+	return false
+}
+
+// Commit records the staged changes on the checked out branch.
+func (p *PolicyRepo) Commit(opts CommitOptions) (*object.Commit, error) {
+	status, err := p.worktree.Status()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree status: %w", err)
+	}
+	return p.commit(status, opts)
+}
+
+func (p *PolicyRepo) commit(status git.Status, opts CommitOptions) (*object.Commit, error) {
+	if opts.Message == nil {
+		return nil, errors.New("no commit message")
+	}
+	message, err := opts.Message(summarize(status))
+	if err != nil {
+		return nil, fmt.Errorf("failed to render commit message: %w", err)
+	}
+
+	hash, err := p.worktree.Commit(message, &git.CommitOptions{
+		Author:    opts.Author,
+		Committer: opts.Committer,
+		SignKey:   opts.SignKey,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	commit, err := p.repo.CommitObject(hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit %s: %w", hash, err)
+	}
+	return commit, nil
+}
+
+// summarize returns the staged changes in status.
+func summarize(status git.Status) Summary {
+	var s Summary
+	for name, file := range status {
+		switch file.Staging {
+		case git.Added:
+			s.Added = append(s.Added, name)
+		case git.Deleted:
+			s.Deleted = append(s.Deleted, name)
+		case git.Modified, git.Renamed, git.Copied:
+			s.Modified = append(s.Modified, name)
+		}
+	}
+	sort.Strings(s.Added)
+	sort.Strings(s.Modified)
+	sort.Strings(s.Deleted)
+	return s
+}
@@ -0,0 +1,150 @@
+package gitstore
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+var testCommit = CommitOptions{
+	Author:  &object.Signature{Name: "Policy Bot", Email: "policy-bot@example.com", When: time.Unix(1500000000, 0)},
+	Message: Message("Update policies"),
+}
+
+// newRemote returns the file:// URL of a bare repository whose master
+// branch holds files in one commit.
+func newRemote(t *testing.T, files map[string][]byte) string {
+	dir, err := ioutil.TempDir("", "remote")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	if _, err := git.PlainInit(dir, true); err != nil {
+		t.Fatal(err)
+	}
+	url := "file://" + dir
+
+	p, err := Init(Options{URL: url, Branch: "master"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.CommitFiles(files, testCommit); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Push(); err != nil {
+		t.Fatal(err)
+	}
+	return url
+}
+
+func openRemote(t *testing.T, url string) *PolicyRepo {
+	p, err := Open(Options{URL: url, Branch: "master"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// tree returns the files of the commit and their content.
+func tree(t *testing.T, commit *object.Commit) map[string]string {
+	files, err := commit.Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents := map[string]string{}
+	err = files.ForEach(func(f *object.File) error {
+		content, err := f.Contents()
+		contents[f.Name] = content
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return contents
+}
+
+func TestCommitFiles(t *testing.T) {
+	url := newRemote(t, map[string][]byte{
+		"policy/a.rego": []byte("package a\n"),
+		"policy/b.rego": []byte("package b\n"),
+	})
+
+	tests := []struct {
+		name  string
+		files map[string][]byte
+		// want is the tree of the commit, nil when nothing is committed
+		want    map[string]string
+		summary Summary
+	}{
+		{
+			name:  "unchanged",
+			files: map[string][]byte{"policy/a.rego": []byte("package a\n")},
+		},
+		{
+			name:  "delete missing file",
+			files: map[string][]byte{"policy/c.rego": nil},
+		},
+		{
+			name: "add, modify and delete",
+			files: map[string][]byte{
+				"policy/a.rego":  []byte("package a\n\nallow = true\n"),
+				"policy/b.rego":  nil,
+				"data/data.json": []byte("{}"),
+			},
+			want: map[string]string{
+				"policy/a.rego":  "package a\n\nallow = true\n",
+				"data/data.json": "{}",
+			},
+			summary: Summary{Added: []string{"data/data.json"}, Modified: []string{"policy/a.rego"}, Deleted: []string{"policy/b.rego"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := openRemote(t, url)
+			head, err := p.Head()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var summary Summary
+			opts := testCommit
+			opts.Message = func(s Summary) (string, error) {
+				summary = s
+				return "Update policies", nil
+			}
+
+			commit, err := p.CommitFiles(tt.files, opts)
+			if err != nil {
+				t.Fatalf("CommitFiles: %v", err)
+			}
+			if tt.want == nil {
+				if commit != nil {
+					t.Fatalf("CommitFiles committed %s, want nil", commit.Hash)
+				}
+				return
+			}
+			if commit == nil {
+				t.Fatal("CommitFiles returned a nil commit")
+			}
+			if len(commit.ParentHashes) != 1 || commit.ParentHashes[0] != head.Hash {
+				t.Errorf("parents = %v, want %s", commit.ParentHashes, head.Hash)
+			}
+			got := tree(t, commit)
+			if len(got) != len(tt.want) {
+				t.Errorf("tree = %v, want %v", got, tt.want)
+			}
+			for name, content := range tt.want {
+				if got[name] != content {
+					t.Errorf("%s = %q, want %q", name, got[name], content)
+				}
+			}
+			if !reflect.DeepEqual(summary, tt.summary) {
+				t.Errorf("summary = %+v, want %+v", summary, tt.summary)
+			}
+		})
+	}
+}
@@ -0,0 +1,478 @@
+// Package gitstore implements the clone, read, write, commit and push
+// workflow used to maintain policy repositories, so services can embed it
+// instead of driving go-git themselves.
+package gitstore
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+
+	"github.com/ashish246/GolangGitExample/src/credentials"
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/format/index"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
+)
+
+// DefaultRemoteName is used when Options.RemoteName is empty.
+const DefaultRemoteName = "origin"
+
+// Options configures how a policy repository is opened.
+type Options struct {
+	// URL of the remote repository. It must not embed credentials.
+	URL string
+	// Branch to check out, commit to and push.
+	Branch string
+	// RemoteName defaults to DefaultRemoteName.
+	RemoteName string
+	// Auth is passed to every remote operation; nil for anonymous access.
+	Auth transport.AuthMethod
+	// Progress receives the server's progress messages if set.
+	Progress io.Writer
+	// Retry bounds the push retries done by Update; the zero value means
+	// DefaultRetryPolicy.
+	Retry RetryPolicy
+	// CacheDir, if set, holds an on-disk clone that Open reuses between
+	// runs, fetching only new commits, instead of cloning into memory.
+	CacheDir string
+	// Depth limits the history fetched to that many commits; 0 fetches it
+	// all. go-git cannot fetch into a shallow clone once the branch has
+	// moved, so it must be 0 with CacheDir, and Reset fails on a shallow
+	// clone after the branch moved.
+	Depth int
+	// SingleBranch clones only Branch rather than every branch.
+	SingleBranch bool
+}
+
+func (o *Options) remoteName() string {
+	if o.RemoteName == "" {
+		return DefaultRemoteName
+	}
+	return o.RemoteName
+}
+
+func (o *Options) branchRef() plumbing.ReferenceName {
+	return plumbing.NewBranchReferenceName(o.Branch)
+}
+
+// PolicyRepo is a working copy of a policy repository checked out at
+// Options.Branch.
+type PolicyRepo struct {
+	opts     Options
+	repo     *git.Repository
+	fs       billy.Filesystem
+	worktree *git.Worktree
+}
+
+func (o *Options) cloneOptions() *git.CloneOptions {
+	return &git.CloneOptions{
+		URL:           o.URL,
+		Auth:          o.Auth,
+		RemoteName:    o.remoteName(),
+		ReferenceName: o.branchRef(),
+		SingleBranch:  o.SingleBranch,
+		Depth:         o.Depth,
+		Progress:      o.Progress,
+	}
+}
+
+// Open clones opts.URL and checks out opts.Branch. The clone is kept in
+// memory unless opts.CacheDir is set, in which case an existing clone in
+// that folder is fetched and reset to the tip of the branch instead.
+func Open(opts Options) (*PolicyRepo, error) {
+	if opts.Branch == "" {
+		return nil, errors.New("no branch to check out")
+	}
+	if opts.CacheDir != "" && opts.Depth > 0 {
+		return nil, errors.New("a cached clone cannot be shallow, set Depth to 0")
+	}
+	if opts.CacheDir != "" {
+		return openCache(opts)
+	}
+
+	// The worktree holds the checked out files, all git objects live in
+	// the storer
+	repo, err := git.Clone(memory.NewStorage(), memfs.New(), opts.cloneOptions())
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone %s at %s: %w", credentials.RedactURL(opts.URL), opts.Branch, credentials.RedactError(err))
+	}
+	return newPolicyRepo(opts, repo)
+}
+
+func openCache(opts Options) (*PolicyRepo, error) {
+	repo, err := git.PlainOpen(opts.CacheDir)
+	if err == git.ErrRepositoryNotExists {
+		return cloneCache(opts)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cache %s: %w", opts.CacheDir, err)
+	}
+
+	remote, err := repo.Remote(opts.remoteName())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get remote %s of cache %s: %w", opts.remoteName(), opts.CacheDir, err)
+	}
+	if urls := remote.Config().URLs; len(urls) == 0 || urls[0] != opts.URL {
+		return nil, fmt.Errorf("cache %s is a clone of another repository than %s", opts.CacheDir, credentials.RedactURL(opts.URL))
+	}
+
+	p, err := newPolicyRepo(opts, repo)
+	if err != nil {
+		return nil, err
+	}
+	err = p.Reset()
+	if errors.Is(err, plumbing.ErrObjectNotFound) && isShallow(repo) {
+		// go-git walks the local history when fetching and fails at the
+		// shallow boundary, so a cache left shallow by an older version is
+		// replaced by a full clone once
+		if err := os.RemoveAll(opts.CacheDir); err != nil {
+			return nil, fmt.Errorf("failed to remove cache %s: %w", opts.CacheDir, err)
+		}
+		return cloneCache(opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func cloneCache(opts Options) (*PolicyRepo, error) {
+	repo, err := git.PlainClone(opts.CacheDir, false, opts.cloneOptions())
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone %s at %s into %s: %w", credentials.RedactURL(opts.URL), opts.Branch, opts.CacheDir, credentials.RedactError(err))
+	}
+	return newPolicyRepo(opts, repo)
+}
+
+func isShallow(repo *git.Repository) bool {
+	commits, err := repo.Storer.Shallow()
+	return err == nil && len(commits) > 0
+}
+
+// Init creates an empty in-memory repository whose remote points at
+// opts.URL. The first Commit creates opts.Branch. opts.CacheDir is ignored.
+func Init(opts Options) (*PolicyRepo, error) {
+	if opts.Branch == "" {
+		return nil, errors.New("no branch to commit to")
+	}
+
+	repo, err := git.Init(memory.NewStorage(), memfs.New())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create in-memory repo: %w", err)
+	}
+	_, err = repo.CreateRemote(&config.RemoteConfig{
+		Name: opts.remoteName(),
+		URLs: []string{opts.URL},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add remote %s: %w", opts.remoteName(), err)
+	}
+	// Point HEAD at the branch so the first commit lands on it
+	head := plumbing.NewSymbolicReference(plumbing.HEAD, opts.branchRef())
+	if err := repo.Storer.SetReference(head); err != nil {
+		return nil, fmt.Errorf("failed to point HEAD at %s: %w", opts.Branch, err)
+	}
+	return newPolicyRepo(opts, repo)
+}
+
+func newPolicyRepo(opts Options, repo *git.Repository) (*PolicyRepo, error) {
+	w, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+	return &PolicyRepo{opts: opts, repo: repo, fs: w.Filesystem, worktree: w}, nil
+}
+
+// Repository returns the underlying go-git repository.
+func (p *PolicyRepo) Repository() *git.Repository {
+	return p.repo
+}
+
+// Branch returns the checked out branch, Options.Branch.
+func (p *PolicyRepo) Branch() string {
+	return p.opts.Branch
+}
+
+// Filesystem returns the worktree filesystem.
+func (p *PolicyRepo) Filesystem() billy.Filesystem {
+	return p.fs
+}
+
+// Head returns the commit HEAD points at.
+func (p *PolicyRepo) Head() (*object.Commit, error) {
+	ref, err := p.repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	commit, err := p.repo.CommitObject(ref.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD commit %s: %w", ref.Hash(), err)
+	}
+	return commit, nil
+}
+
+// ReadFile returns the content of name in the worktree. The error wraps
+// os.ErrNotExist when the file does not exist.
+func (p *PolicyRepo) ReadFile(name string) ([]byte, error) {
+	f, err := p.fs.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer f.Close()
+
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return data, nil
+}
+
+// ReadFileAt returns the content of name in the tree of commit. The error
+// wraps os.ErrNotExist when the file does not exist there.
+func (p *PolicyRepo) ReadFileAt(commit *object.Commit, name string) ([]byte, error) {
+	f, err := fileAt(commit, name)
+	if err != nil {
+		return nil, err
+	}
+	if f == nil {
+		return nil, fmt.Errorf("%s does not exist at %s: %w", name, commit.Hash, os.ErrNotExist)
+	}
+	data, err := contents(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s at %s: %w", name, commit.Hash, err)
+	}
+	return data, nil
+}
+
+// ListFiles returns the slash separated paths of all files below dir in
+// the worktree, in lexical order. A missing dir yields no files and the
+// .git folder of an on-disk clone is skipped.
+func (p *PolicyRepo) ListFiles(dir string) ([]string, error) {
+	var files []string
+	var walk func(dir string) error
+	walk = func(dir string) error {
+		infos, err := p.fs.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, info := range infos {
+			name := path.Join(dir, info.Name())
+			if name == git.GitDirName {
+				continue
+			}
+			if info.IsDir() {
+				if err := walk(name); err != nil {
+					return err
+				}
+				continue
+			}
+			files = append(files, name)
+		}
+		return nil
+	}
+
+	err := walk(path.Clean(dir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// WriteFiles replaces the content of each file, creating parent folders as
+// needed, and stages them for the next Commit. A nil content deletes the
+// file; deleting a file that does not exist is not an error.
+func (p *PolicyRepo) WriteFiles(files map[string][]byte) error {
+	for name, content := range files {
+		if content == nil {
+			if err := p.remove(name); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := p.fs.MkdirAll(path.Dir(name), 0755); err != nil {
+			return fmt.Errorf("failed to create folder for %s: %w", name, err)
+		}
+		f, err := p.fs.OpenFile(name, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", name, err)
+		}
+		_, err = f.Write(content)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+		if _, err := p.worktree.Add(name); err != nil {
+			return fmt.Errorf("failed to stage %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (p *PolicyRepo) remove(name string) error {
+	_, err := p.worktree.Remove(name)
+	if errors.Is(err, index.ErrEntryNotFound) {
+		// Not tracked, but may still exist in the worktree
+		err = p.fs.Remove(name)
+		if os.IsNotExist(err) {
+			return nil
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %w", name, err)
+	}
+	return nil
+}
+
+// Push pushes the checked out branch to the remote. Pushing a branch that
+// is already up to date is not an error. The error wraps ErrNonFastForward
+// when the remote branch has moved; use Update to retry in that case.
+func (p *PolicyRepo) Push() error {
+	return p.push(p.opts.Branch)
+}
+
+// PushBranch pushes the checked out branch, including commits not pushed
+// yet, to the remote branch name, e.g. to propose them in a pull request
+// rather than pushing them to Options.Branch. The checked out branch is
+// left as it is.
+func (p *PolicyRepo) PushBranch(name string) error {
+	return p.push(name)
+}
+
+func (p *PolicyRepo) push(branch string) error {
+	refSpec := fmt.Sprintf("%s:%s", p.opts.branchRef(), plumbing.NewBranchReferenceName(branch))
+	err := p.repo.Push(&git.PushOptions{
+		RemoteName: p.opts.remoteName(),
+		RefSpecs:   []config.RefSpec{config.RefSpec(refSpec)},
+		Auth:       p.opts.Auth,
+		Progress:   p.opts.Progress,
+	})
+	if err == nil || err == git.NoErrAlreadyUpToDate {
+		return nil
+	}
+	if isNonFastForward(err) {
+		return fmt.Errorf("failed to push %s to %s: %w (%v)", branch, p.opts.remoteName(), ErrNonFastForward, credentials.RedactError(err))
+	}
+	return fmt.Errorf("failed to push %s to %s: %w", branch, p.opts.remoteName(), credentials.RedactError(err))
+}
+
+// Pull fetches the branch from the remote and fast-forwards the worktree.
+// Pulling a branch that is already up to date is not an error.
+func (p *PolicyRepo) Pull() error {
+	err := p.worktree.Pull(&git.PullOptions{
+		RemoteName:    p.opts.remoteName(),
+		ReferenceName: p.opts.branchRef(),
+		SingleBranch:  true,
+		Depth:         p.opts.Depth,
+		Auth:          p.opts.Auth,
+		Progress:      p.opts.Progress,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return fmt.Errorf("failed to pull %s from %s: %w", p.opts.Branch, p.opts.remoteName(), credentials.RedactError(err))
+	}
+	return nil
+}
+
+// ErrRefNotFound is wrapped by the error returned by Resolve and Checkout
+// when the ref is neither known locally nor on the remote.
+var ErrRefNotFound = errors.New("ref not found")
+
+// Checkout checks out ref, a tag, a branch or a full commit hash, with a
+// detached HEAD and returns the commit it resolves to. Tags and branches
+// are fetched from the remote first, see Resolve.
+func (p *PolicyRepo) Checkout(ref string) (*object.Commit, error) {
+	commit, err := p.Resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.worktree.Checkout(&git.CheckoutOptions{Hash: commit.Hash, Force: true}); err != nil {
+		return nil, fmt.Errorf("failed to check out %s: %w", commit.Hash, err)
+	}
+	return commit, nil
+}
+
+// Resolve returns the commit ref points at without checking it out. Besides
+// what Checkout accepts, ref may be a revision such as HEAD~1. Branches and
+// tags are fetched from the remote before resolving anything but a full
+// commit hash, since they may have moved since an earlier run fetched them
+// into a cached clone. A full hash is only fetched when it is not known
+// locally, as after a single branch clone.
+func (p *PolicyRepo) Resolve(ref string) (*object.Commit, error) {
+	fetched := false
+	if !isFullHash(ref) {
+		if err := p.fetchRefs(); err != nil {
+			return nil, err
+		}
+		fetched = true
+	}
+	hash, err := p.resolve(ref)
+	if err != nil && !fetched {
+		if err := p.fetchRefs(); err != nil {
+			return nil, err
+		}
+		hash, err = p.resolve(ref)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %q in %s: %w", ref, credentials.RedactURL(p.opts.URL), ErrRefNotFound)
+	}
+
+	commit, err := p.repo.CommitObject(hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit %s: %w", hash, err)
+	}
+	return commit, nil
+}
+
+// isFullHash reports whether ref is a 40 digit hexadecimal commit hash.
+func isFullHash(ref string) bool {
+	if len(ref) != 40 {
+		return false
+	}
+	_, err := hex.DecodeString(ref)
+	return err == nil
+}
+
+// resolve returns the commit ref names, trying it as a remote branch first
+// since fetchRefs does not update local branches, which a cached clone may
+// have kept from an earlier run.
+func (p *PolicyRepo) resolve(ref string) (plumbing.Hash, error) {
+	var err error
+	for _, rev := range []string{p.opts.remoteName() + "/" + ref, ref} {
+		var hash *plumbing.Hash
+		if hash, err = p.repo.ResolveRevision(plumbing.Revision(rev)); err == nil {
+			return *hash, nil
+		}
+	}
+	return plumbing.ZeroHash, err
+}
+
+// fetchRefs fetches all branches and tags from the remote.
+func (p *PolicyRepo) fetchRefs() error {
+	err := p.repo.Fetch(&git.FetchOptions{
+		RemoteName: p.opts.remoteName(),
+		RefSpecs: []config.RefSpec{
+			config.RefSpec(fmt.Sprintf("+refs/heads/*:refs/remotes/%s/*", p.opts.remoteName())),
+			"+refs/tags/*:refs/tags/*",
+		},
+		Auth:     p.opts.Auth,
+		Progress: p.opts.Progress,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return fmt.Errorf("failed to fetch refs from %s: %w", p.opts.remoteName(), credentials.RedactError(err))
+	}
+	return nil
+}
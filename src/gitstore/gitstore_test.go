@@ -0,0 +1,130 @@
+package gitstore
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+func TestResolveFetchesMovedRefs(t *testing.T) {
+	url := newRemote(t, map[string][]byte{"README.md": []byte("policies\n")})
+	remote, err := git.PlainOpen(strings.TrimPrefix(url, "file://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag := func(hash plumbing.Hash) {
+		if err := remote.Storer.SetReference(plumbing.NewHashReference("refs/tags/v1", hash)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	other := openRemote(t, url)
+	first, err := other.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag(first.Hash)
+	if err := other.PushBranch("release"); err != nil {
+		t.Fatal(err)
+	}
+
+	p := openRemote(t, url)
+	for ref, want := range map[string]plumbing.Hash{"v1": first.Hash, "release": first.Hash} {
+		if got, err := p.Resolve(ref); err != nil || got.Hash != want {
+			t.Fatalf("Resolve(%q) = %v, %v, want %s", ref, got, err, want)
+		}
+	}
+
+	// Move the branch and the tag on the remote; p has both from before
+	second, err := other.CommitFiles(map[string][]byte{"policy.rego": []byte("package policy\n")}, testCommit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.PushBranch("release"); err != nil {
+		t.Fatal(err)
+	}
+	tag(second.Hash)
+
+	tests := map[string]plumbing.Hash{
+		"v1":                 second.Hash,
+		"release":            second.Hash,
+		"release~1":          first.Hash,
+		second.Hash.String(): second.Hash,
+	}
+	for ref, want := range tests {
+		got, err := p.Resolve(ref)
+		if err != nil {
+			t.Errorf("Resolve(%q): %v", ref, err)
+			continue
+		}
+		if got.Hash != want {
+			t.Errorf("Resolve(%q) = %s, want %s", ref, got.Hash, want)
+		}
+	}
+
+	if _, err := p.Resolve("missing"); !errors.Is(err, ErrRefNotFound) {
+		t.Errorf("Resolve(missing) error = %v, want ErrRefNotFound", err)
+	}
+}
+
+func TestOpenCache(t *testing.T) {
+	url := newRemote(t, map[string][]byte{"README.md": []byte("policies\n")})
+	other := openRemote(t, url)
+	if _, err := other.Update(Files(map[string][]byte{"a.rego": []byte("package a\n")}), testCommit); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	opts := Options{URL: url, Branch: "master", CacheDir: dir}
+	if _, err := Open(Options{URL: url, Branch: "master", CacheDir: dir, Depth: 1}); err == nil {
+		t.Fatal("Open accepted a shallow cache")
+	}
+
+	// Caches left shallow by older versions are replaced by a full clone
+	if _, err := git.PlainClone(dir, false, &git.CloneOptions{URL: url, Depth: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Update(Files(map[string][]byte{"a.rego": []byte("package a\n# shallow\n")}), testCommit); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(opts); err != nil {
+		t.Fatalf("Open of a shallow cache: %v", err)
+	}
+	// A clone made again would not have the marker
+	marker := filepath.Join(dir, git.GitDirName, "marker")
+	if err := ioutil.WriteFile(marker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		pushed, err := other.Update(Files(map[string][]byte{"a.rego": []byte(fmt.Sprintf("package a\n# %d\n", i))}), testCommit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := Open(opts)
+		if err != nil {
+			t.Fatalf("Open after the remote moved: %v", err)
+		}
+		head, err := p.Head()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if head.Hash != pushed.Hash {
+			t.Errorf("cache at %s, want the new tip %s", head.Hash, pushed.Hash)
+		}
+		if _, err := os.Stat(marker); err != nil {
+			t.Fatalf("cache was cloned again: %v", err)
+		}
+	}
+}
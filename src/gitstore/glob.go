@@ -0,0 +1,79 @@
+package gitstore
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Glob returns the files below dir in the worktree matching at least one
+// include pattern and no exclude pattern, relative to dir and in lexical
+// order. Patterns use the path.Match syntax on slash separated paths
+// relative to dir, plus "**" as a whole element matching any number of
+// folders, e.g. "uam2/**/*.rego". Use "." for the repository root.
+func (p *PolicyRepo) Glob(dir string, include, exclude []string) ([]string, error) {
+	for _, pattern := range append(append([]string(nil), include...), exclude...) {
+		if err := ValidatePattern(pattern); err != nil {
+			return nil, err
+		}
+	}
+
+	dir = path.Clean(dir)
+	files, err := p.ListFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	var matched []string
+	for _, file := range files {
+		if dir != "." {
+			file = strings.TrimPrefix(file, dir+"/")
+		}
+		if MatchAny(include, file) && !MatchAny(exclude, file) {
+			matched = append(matched, file)
+		}
+	}
+	return matched, nil
+}
+
+// ValidatePattern reports whether pattern is well formed for Glob.
+func ValidatePattern(pattern string) error {
+	for _, elem := range strings.Split(pattern, "/") {
+		if _, err := path.Match(elem, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// MatchAny reports whether name matches one of the patterns, as described
+// for Glob. Malformed patterns match nothing.
+func MatchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if match(strings.Split(pattern, "/"), strings.Split(name, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+func match(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Try every number of folders for the "**" element
+			for i := 0; i <= len(name); i++ {
+				if match(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,86 @@
+package gitstore
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchAny(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"opa-policy.rego", "opa-policy.rego", true},
+		{"opa-policy.rego", "uam2/opa-policy.rego", false},
+		{"*.rego", "policy.rego", true},
+		{"*.rego", "uam2/policy.rego", false},
+		{"uam2/*.rego", "uam2/policy.rego", true},
+		{"uam2/*.rego", "uam2/common/policy.rego", false},
+		{"uam2/**/*.rego", "uam2/policy.rego", true},
+		{"uam2/**/*.rego", "uam2/common/policy.rego", true},
+		{"uam2/**/*.rego", "uam2/common/deep/policy.rego", true},
+		{"uam2/**/*.rego", "other/policy.rego", false},
+		{"uam2/**/*.rego", "uam2/common/policy.json", false},
+		{"**/*_test.rego", "policy_test.rego", true},
+		{"**/*_test.rego", "uam2/common/policy_test.rego", true},
+		{"**", "uam2/common/policy.rego", true},
+		{"uam2/**", "uam2", true},
+		{"uam2/**", "uam2x/policy.rego", false},
+		{"uam2/*/policy.rego", "uam2/a/b/policy.rego", false},
+		{"uam2/?/policy.rego", "uam2/a/policy.rego", true},
+		{"[", "[", false},
+	}
+	for _, tt := range tests {
+		if got := MatchAny([]string{tt.pattern}, tt.name); got != tt.want {
+			t.Errorf("MatchAny(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidatePattern(t *testing.T) {
+	for pattern, valid := range map[string]bool{
+		"uam2/**/*.rego": true,
+		"[a-z]*.rego":    true,
+		"uam2/[/x.rego":  false,
+		"a\\":            false,
+	} {
+		if err := ValidatePattern(pattern); (err == nil) != valid {
+			t.Errorf("ValidatePattern(%q) = %v, want valid %v", pattern, err, valid)
+		}
+	}
+}
+
+func TestGlob(t *testing.T) {
+	url := newRemote(t, map[string][]byte{
+		"opa-policy.rego":               []byte("package opa\n"),
+		"uam2/policy.rego":              []byte("package uam2\n"),
+		"uam2/policy_test.rego":         []byte("package uam2\n"),
+		"uam2/common/helpers.rego":      []byte("package uam2.common\n"),
+		"uam2/common/helpers_test.rego": []byte("package uam2.common\n"),
+		"uam2/data.json":                []byte("{}"),
+	})
+	p := openRemote(t, url)
+
+	tests := []struct {
+		dir              string
+		include, exclude []string
+		want             []string
+	}{
+		{".", []string{"*.rego"}, nil, []string{"opa-policy.rego"}},
+		{".", []string{"**/*.rego"}, []string{"**/*_test.rego"}, []string{"opa-policy.rego", "uam2/common/helpers.rego", "uam2/policy.rego"}},
+		{"uam2", []string{"*.rego", "*.json"}, []string{"*_test.rego"}, []string{"data.json", "policy.rego"}},
+		{"uam2", []string{"**"}, []string{"common/**"}, []string{"data.json", "policy.rego", "policy_test.rego"}},
+		{"missing", []string{"**"}, nil, nil},
+	}
+	for _, tt := range tests {
+		got, err := p.Glob(tt.dir, tt.include, tt.exclude)
+		if err != nil {
+			t.Errorf("Glob(%q, %q, %q): %v", tt.dir, tt.include, tt.exclude, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Glob(%q, %q, %q) = %q, want %q", tt.dir, tt.include, tt.exclude, got, tt.want)
+		}
+	}
+}
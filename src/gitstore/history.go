@@ -0,0 +1,99 @@
+package gitstore
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// Revision is the content of a file as of a commit that changed it.
+type Revision struct {
+	Commit *object.Commit
+	// Content is nil when the commit deleted the file.
+	Content []byte
+}
+
+// History returns a Revision for every commit that added, changed or
+// deleted the file name, oldest first. Only the first parent of merges is
+// followed, so changes merged into the branch are attributed to the merge
+// commit, as with git log --first-parent. Commits beyond the depth of a
+// shallow clone are not included.
+func (p *PolicyRepo) History(name string) ([]Revision, error) {
+	c, err := p.Head()
+	if err != nil {
+		return nil, err
+	}
+
+	var revisions []Revision
+	for c != nil {
+		file, err := fileAt(c, name)
+		if err != nil {
+			return nil, err
+		}
+
+		var parent *object.Commit
+		if c.NumParents() > 0 {
+			parent, err = c.Parent(0)
+			if err == plumbing.ErrObjectNotFound {
+				// The shallow boundary, treat c as the first commit
+				parent, err = nil, nil
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to get parent of %s: %w", c.Hash, err)
+			}
+		}
+		var parentFile *object.File
+		if parent != nil {
+			if parentFile, err = fileAt(parent, name); err != nil {
+				return nil, err
+			}
+		}
+
+		if changed(file, parentFile) {
+			rev := Revision{Commit: c}
+			if file != nil {
+				if rev.Content, err = contents(file); err != nil {
+					return nil, fmt.Errorf("failed to read %s at %s: %w", name, c.Hash, err)
+				}
+			}
+			revisions = append(revisions, rev)
+		}
+		c = parent
+	}
+
+	// The walk went from newest to oldest
+	for i, j := 0, len(revisions)-1; i < j; i, j = i+1, j-1 {
+		revisions[i], revisions[j] = revisions[j], revisions[i]
+	}
+	return revisions, nil
+}
+
+// fileAt returns name in the tree of c, nil if it does not exist there.
+func fileAt(c *object.Commit, name string) (*object.File, error) {
+	f, err := c.File(name)
+	if err == object.ErrFileNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s at %s: %w", name, c.Hash, err)
+	}
+	return f, nil
+}
+
+func changed(file, parent *object.File) bool {
+	if file == nil || parent == nil {
+		return file != parent
+	}
+	return file.Hash != parent.Hash
+}
+
+func contents(f *object.File) ([]byte, error) {
+	r, err := f.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
@@ -0,0 +1,55 @@
+package gitstore
+
+import "testing"
+
+func TestHistory(t *testing.T) {
+	url := newRemote(t, map[string][]byte{"README.md": []byte("policies\n")})
+	p := openRemote(t, url)
+	steps := []struct {
+		files map[string][]byte
+		// content is what the commit sets the file to, "" when it is not
+		// changed and "-" when it is deleted
+		content string
+	}{
+		{map[string][]byte{"entitlements.yml": []byte("v1")}, "v1"},
+		{map[string][]byte{"README.md": []byte("policies, updated\n")}, ""},
+		{map[string][]byte{"entitlements.yml": []byte("v2")}, "v2"},
+		{map[string][]byte{"entitlements.yml": nil}, "-"},
+		{map[string][]byte{"entitlements.yml": []byte("v3")}, "v3"},
+	}
+	var want []string
+	for _, s := range steps {
+		commit, err := p.CommitFiles(s.files, testCommit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.content != "" {
+			want = append(want, commit.Hash.String()+" "+s.content)
+		}
+	}
+
+	revisions, err := p.History("entitlements.yml")
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	var got []string
+	for _, rev := range revisions {
+		content := string(rev.Content)
+		if rev.Content == nil {
+			content = "-"
+		}
+		got = append(got, rev.Commit.Hash.String()+" "+content)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("History = %q, want %q", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("revision %d = %s, want %s", i, got[i], want[i])
+		}
+	}
+
+	if revisions, err := p.History("missing.yml"); err != nil || len(revisions) != 0 {
+		t.Errorf("History of a file never committed = %d revisions, %v", len(revisions), err)
+	}
+}
@@ -0,0 +1,172 @@
+package gitstore
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ashish246/GolangGitExample/src/credentials"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// ErrNonFastForward is wrapped by the error returned by Push when the remote
+// branch has moved since it was fetched.
+var ErrNonFastForward = errors.New("push rejected, remote branch has new commits")
+
+// RetryPolicy bounds how often a rejected push is retried by Update.
+type RetryPolicy struct {
+	// Attempts is the total number of pushes tried, at least one.
+	Attempts int
+	// Backoff is the wait before the first retry. It doubles after every
+	// retry up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy is used when Options.Retry is the zero value.
+var DefaultRetryPolicy = RetryPolicy{
+	Attempts:   5,
+	Backoff:    time.Second,
+	MaxBackoff: 30 * time.Second,
+}
+
+// RetriesExhaustedError is returned by Update when every push attempt was
+// rejected because another writer kept pushing to the branch first.
+type RetriesExhaustedError struct {
+	Branch   string
+	Attempts int
+	Err      error
+}
+
+func (e *RetriesExhaustedError) Error() string {
+	return fmt.Sprintf("gave up pushing %s after %d attempts: %v", e.Branch, e.Attempts, e.Err)
+}
+
+func (e *RetriesExhaustedError) Unwrap() error {
+	return e.Err
+}
+
+// isNonFastForward reports whether err is a push rejection caused by the
+// remote branch having moved. go-git detects most of these itself before
+// sending anything; the server reports the rest as "non-fast-forward" or
+// "fetch first".
+func isNonFastForward(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "non-fast-forward") || strings.Contains(msg, "fetch first")
+}
+
+// UpdateFunc returns the files to commit, as accepted by CommitFiles, for
+// the repository checked out at the tip of the branch. Update calls it again
+// after every rejected push so the files can be derived from the new tip.
+type UpdateFunc func(p *PolicyRepo) (map[string][]byte, error)
+
+// Files returns an UpdateFunc that always writes files.
+func Files(files map[string][]byte) UpdateFunc {
+	return func(*PolicyRepo) (map[string][]byte, error) {
+		return files, nil
+	}
+}
+
+// Update commits the files returned by update in a single commit and pushes
+// it. When the push is rejected because the branch moved, the branch is
+// fetched again, the worktree is reset to the new tip, the files are
+// re-applied and the push is retried following Options.Retry. A nil commit
+// is returned when the files are already up to date on the branch.
+func (p *PolicyRepo) Update(update UpdateFunc, opts CommitOptions) (*object.Commit, error) {
+	retry := p.opts.Retry
+	if retry.Attempts < 1 {
+		retry = DefaultRetryPolicy
+	}
+	backoff := retry.Backoff
+
+	for attempt := 1; ; attempt++ {
+		files, err := update(p)
+		if err != nil {
+			return nil, err
+		}
+		commit, err := p.CommitFiles(files, opts)
+		if err != nil || commit == nil {
+			return nil, err
+		}
+
+		err = p.Push()
+		if err == nil {
+			return commit, nil
+		}
+		if !errors.Is(err, ErrNonFastForward) {
+			return nil, err
+		}
+		if attempt >= retry.Attempts {
+			return nil, &RetriesExhaustedError{Branch: p.opts.Branch, Attempts: attempt, Err: err}
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+		if retry.MaxBackoff > 0 && backoff > retry.MaxBackoff {
+			backoff = retry.MaxBackoff
+		}
+
+		if err := p.Reset(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Reset fetches the branch from the remote, checks it out and hard resets
+// it and the worktree to its tip, discarding local commits, changes and
+// untracked files.
+func (p *PolicyRepo) Reset() error {
+	remoteRef := plumbing.NewRemoteReferenceName(p.opts.remoteName(), p.opts.Branch)
+	refSpec := config.RefSpec(fmt.Sprintf("+%s:%s", p.opts.branchRef(), remoteRef))
+
+	err := p.repo.Fetch(&git.FetchOptions{
+		RemoteName: p.opts.remoteName(),
+		RefSpecs:   []config.RefSpec{refSpec},
+		Auth:       p.opts.Auth,
+		Progress:   p.opts.Progress,
+		Force:      true,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return fmt.Errorf("failed to fetch %s from %s: %w", p.opts.Branch, p.opts.remoteName(), credentials.RedactError(err))
+	}
+
+	ref, err := p.repo.Reference(remoteRef, true)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", remoteRef, err)
+	}
+	if err := p.checkout(ref.Hash()); err != nil {
+		return err
+	}
+	err = p.worktree.Reset(&git.ResetOptions{Commit: ref.Hash(), Mode: git.HardReset})
+	if err != nil {
+		return fmt.Errorf("failed to reset %s to %s: %w", p.opts.Branch, ref.Hash(), err)
+	}
+	if err := p.worktree.Clean(&git.CleanOptions{Dir: true}); err != nil {
+		return fmt.Errorf("failed to remove untracked files: %w", err)
+	}
+	return nil
+}
+
+// checkout switches HEAD to the branch, creating it at hash if it does not
+// exist yet, unless HEAD already points at it. A cached clone may have been
+// left on another branch by an earlier run.
+func (p *PolicyRepo) checkout(hash plumbing.Hash) error {
+	head, err := p.repo.Storer.Reference(plumbing.HEAD)
+	if err == nil && head.Target() == p.opts.branchRef() {
+		return nil
+	}
+
+	opts := &git.CheckoutOptions{Branch: p.opts.branchRef(), Force: true}
+	if _, err := p.repo.Reference(p.opts.branchRef(), false); err == plumbing.ErrReferenceNotFound {
+		opts.Hash = hash
+		opts.Create = true
+	}
+	if err := p.worktree.Checkout(opts); err != nil {
+		return fmt.Errorf("failed to check out %s: %w", p.opts.Branch, err)
+	}
+	return nil
+}
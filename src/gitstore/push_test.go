@@ -0,0 +1,94 @@
+package gitstore
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+var testRetry = RetryPolicy{Attempts: 3, Backoff: time.Millisecond}
+
+func TestUpdateRetriesOnNewTip(t *testing.T) {
+	url := newRemote(t, map[string][]byte{"README.md": []byte("policies\n")})
+	p := openRemote(t, url)
+	p.opts.Retry = testRetry
+
+	// Another writer pushes after p cloned, so p's first push is rejected
+	other := openRemote(t, url)
+	pushed, err := other.Update(Files(map[string][]byte{"other.rego": []byte("package other\n")}), testCommit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls int
+	var tips []string
+	update := func(p *PolicyRepo) (map[string][]byte, error) {
+		calls++
+		head, err := p.Head()
+		if err != nil {
+			return nil, err
+		}
+		tips = append(tips, head.Hash.String())
+		return map[string][]byte{"policy.rego": []byte("package policy\n")}, nil
+	}
+	commit, err := p.Update(update, testCommit)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("update called %d times, want 2", calls)
+	}
+	if tips[1] != pushed.Hash.String() {
+		t.Errorf("files re-applied on %s, want the new tip %s", tips[1], pushed.Hash)
+	}
+	if len(commit.ParentHashes) != 1 || commit.ParentHashes[0] != pushed.Hash {
+		t.Errorf("parents = %v, want %s", commit.ParentHashes, pushed.Hash)
+	}
+	files := tree(t, commit)
+	if files["other.rego"] != "package other\n" || files["policy.rego"] != "package policy\n" {
+		t.Errorf("tree = %v, want both writers' files", files)
+	}
+
+	fresh := openRemote(t, url)
+	head, err := fresh.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Hash != commit.Hash {
+		t.Errorf("remote master = %s, want %s", head.Hash, commit.Hash)
+	}
+}
+
+func TestUpdateRetriesExhausted(t *testing.T) {
+	url := newRemote(t, map[string][]byte{"README.md": []byte("policies\n")})
+	p := openRemote(t, url)
+	p.opts.Retry = testRetry
+	other := openRemote(t, url)
+
+	// The other writer pushes before every attempt of p
+	var calls int
+	update := func(*PolicyRepo) (map[string][]byte, error) {
+		calls++
+		files := map[string][]byte{"other.rego": []byte(fmt.Sprintf("package other\n# %d\n", calls))}
+		if _, err := other.Update(Files(files), testCommit); err != nil {
+			return nil, err
+		}
+		return map[string][]byte{"policy.rego": []byte("package policy\n")}, nil
+	}
+	_, err := p.Update(update, testCommit)
+
+	var exhausted *RetriesExhaustedError
+	if !errors.As(err, &exhausted) {
+		t.Fatalf("Update error = %v, want a *RetriesExhaustedError", err)
+	}
+	if exhausted.Attempts != testRetry.Attempts || exhausted.Branch != "master" {
+		t.Errorf("error = %+v, want %d attempts on master", exhausted, testRetry.Attempts)
+	}
+	if !errors.Is(err, ErrNonFastForward) {
+		t.Errorf("error %v does not wrap ErrNonFastForward", err)
+	}
+	if calls != testRetry.Attempts {
+		t.Errorf("update called %d times, want %d", calls, testRetry.Attempts)
+	}
+}
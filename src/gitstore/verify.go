@@ -0,0 +1,40 @@
+package gitstore
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/openpgp"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// ErrUnsigned is wrapped by the error returned by Verify for commits that
+// carry no signature.
+var ErrUnsigned = errors.New("commit is not signed")
+
+// Verify checks the OpenPGP signature of commit against the armored public
+// keyring and returns the key that made it.
+func Verify(commit *object.Commit, armoredKeyRing string) (*openpgp.Entity, error) {
+	if commit.PGPSignature == "" {
+		return nil, fmt.Errorf("failed to verify %s: %w", commit.Hash, ErrUnsigned)
+	}
+	entity, err := commit.Verify(armoredKeyRing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify signature of %s: %w", commit.Hash, err)
+	}
+	return entity, nil
+}
+
+// VerifyHead verifies the signature of the commit HEAD points at, see
+// Verify.
+func (p *PolicyRepo) VerifyHead(armoredKeyRing string) (*object.Commit, *openpgp.Entity, error) {
+	head, err := p.Head()
+	if err != nil {
+		return nil, nil, err
+	}
+	entity, err := Verify(head, armoredKeyRing)
+	if err != nil {
+		return nil, nil, err
+	}
+	return head, entity, nil
+}
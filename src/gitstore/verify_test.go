@@ -0,0 +1,86 @@
+package gitstore
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ashish246/GolangGitExample/src/credentials"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+// newSigningKey returns a throwaway OpenPGP key read back through
+// credentials.SigningKey, and its armored public keyring.
+func newSigningKey(t *testing.T, name string) (*openpgp.Entity, string) {
+	entity, err := openpgp.NewEntity(name, "", "policy-bot@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var private bytes.Buffer
+	w, err := armor.Encode(&private, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.SerializePrivate(w, nil); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	t.Setenv("OPA_TEST_GPG_SIGNING_KEY", private.String())
+	key, err := credentials.SigningKey(credentials.EnvProvider{Prefix: "OPA_TEST_"})
+	if err != nil {
+		t.Fatalf("SigningKey: %v", err)
+	}
+
+	var public bytes.Buffer
+	w, err = armor.Encode(&public, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	return key, public.String()
+}
+
+func TestVerify(t *testing.T) {
+	key, keyRing := newSigningKey(t, "Policy Bot")
+	foreign, _ := newSigningKey(t, "Someone Else")
+	p := openRemote(t, newRemote(t, map[string][]byte{"README.md": []byte("policies\n")}))
+
+	signed := testCommit
+	signed.SignKey = key
+	commit, err := p.CommitFiles(map[string][]byte{"policy/a.rego": []byte("package a\n")}, signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entity, err := Verify(commit, keyRing)
+	if err != nil {
+		t.Fatalf("Verify of a signed commit: %v", err)
+	}
+	if entity.PrimaryKey.KeyId != key.PrimaryKey.KeyId {
+		t.Errorf("Verify returned key %s, want %s", entity.PrimaryKey.KeyIdString(), key.PrimaryKey.KeyIdString())
+	}
+	if head, _, err := p.VerifyHead(keyRing); err != nil || head.Hash != commit.Hash {
+		t.Errorf("VerifyHead = %v, %v", head, err)
+	}
+
+	unsigned, err := p.CommitFiles(map[string][]byte{"policy/b.rego": []byte("package b\n")}, testCommit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Verify(unsigned, keyRing); !errors.Is(err, ErrUnsigned) {
+		t.Errorf("Verify of an unsigned commit error = %v, want ErrUnsigned", err)
+	}
+
+	signed.SignKey = foreign
+	other, err := p.CommitFiles(map[string][]byte{"policy/c.rego": []byte("package c\n")}, signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Verify(other, keyRing); err == nil || errors.Is(err, ErrUnsigned) {
+		t.Errorf("Verify of a commit signed by a foreign key error = %v", err)
+	}
+}
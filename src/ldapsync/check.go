@@ -0,0 +1,121 @@
+package ldapsync
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Kinds of Problem found by Check.
+const (
+	// ProblemNotListed is a user whose memberOf names a group that does
+	// not list the user as a member.
+	ProblemNotListed = "not-listed"
+	// ProblemMemberOfMissing is a group listing a user whose memberOf does
+	// not name the group.
+	ProblemMemberOfMissing = "memberof-missing"
+	// ProblemUnknownGroup is a user whose memberOf names a group that is
+	// not in the snapshot.
+	ProblemUnknownGroup = "unknown-group"
+	// ProblemDanglingMember is a member of a group that matches no user
+	// or group.
+	ProblemDanglingMember = "dangling-member"
+	// ProblemEmptyGroup is a group without members.
+	ProblemEmptyGroup = "empty-group"
+	// ProblemNestingCycle is a group that is a member of itself through
+	// nested groups.
+	ProblemNestingCycle = "nesting-cycle"
+)
+
+// Problem is an inconsistency between the user and group snapshots.
+type Problem struct {
+	Kind  string `json:"kind"`
+	Group string `json:"group"`
+	// Member is the common name of the user or member concerned, empty
+	// for ProblemEmptyGroup. For ProblemNestingCycle it lists the groups
+	// of the cycle, separated by " > ".
+	Member string `json:"member,omitempty"`
+}
+
+func (p Problem) String() string {
+	switch p.Kind {
+	case ProblemNotListed:
+		return fmt.Sprintf("user %q is memberOf %q but the group does not list them", p.Member, p.Group)
+	case ProblemMemberOfMissing:
+		return fmt.Sprintf("group %q lists %q but the user is not memberOf it", p.Group, p.Member)
+	case ProblemUnknownGroup:
+		return fmt.Sprintf("user %q is memberOf %q which does not exist", p.Member, p.Group)
+	case ProblemDanglingMember:
+		return fmt.Sprintf("group %q lists %q which matches no user or group", p.Group, p.Member)
+	case ProblemEmptyGroup:
+		return fmt.Sprintf("group %q has no members", p.Group)
+	case ProblemNestingCycle:
+		return fmt.Sprintf("group %q is a member of itself through %s", p.Group, p.Member)
+	}
+	return fmt.Sprintf("%s: group %q member %q", p.Kind, p.Group, p.Member)
+}
+
+// Check resolves the membership of the snapshots in both directions, the
+// memberOf of every user against the member list of every group, and
+// returns the inconsistencies sorted by group, member and kind. Members
+// that are groups are nested groups and only checked for cycles.
+func Check(users *UserSnapshot, groups *GroupSnapshot) []Problem {
+	var problems []Problem
+	for name, u := range users.Users {
+		for _, group := range u.MemberOf {
+			g, ok := groups.Groups[group]
+			switch {
+			case !ok:
+				problems = append(problems, Problem{Kind: ProblemUnknownGroup, Group: group, Member: name})
+			case !contains(g.Member, name):
+				problems = append(problems, Problem{Kind: ProblemNotListed, Group: group, Member: name})
+			}
+		}
+	}
+	for name, g := range groups.Groups {
+		if len(g.Member) == 0 {
+			problems = append(problems, Problem{Kind: ProblemEmptyGroup, Group: name})
+		}
+		for _, member := range g.Member {
+			u, ok := users.Users[member]
+			_, nested := groups.Groups[member]
+			switch {
+			case !ok && nested:
+			case !ok:
+				problems = append(problems, Problem{Kind: ProblemDanglingMember, Group: name, Member: member})
+			case !contains(u.MemberOf, name):
+				problems = append(problems, Problem{Kind: ProblemMemberOfMissing, Group: name, Member: member})
+			}
+		}
+	}
+	index := NewIndex(users, groups)
+	for name := range groups.Groups {
+		for _, m := range index.NestedGroupsOf(name) {
+			if m.Group.CommonName == name {
+				cycle := strings.Join(append(m.Via, name), " > ")
+				problems = append(problems, Problem{Kind: ProblemNestingCycle, Group: name, Member: cycle})
+			}
+		}
+	}
+
+	sort.Slice(problems, func(i, j int) bool {
+		a, b := problems[i], problems[j]
+		if a.Group != b.Group {
+			return a.Group < b.Group
+		}
+		if a.Member != b.Member {
+			return a.Member < b.Member
+		}
+		return a.Kind < b.Kind
+	})
+	return problems
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
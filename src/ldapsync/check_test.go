@@ -0,0 +1,83 @@
+package ldapsync
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckShippedSnapshots(t *testing.T) {
+	users, err := ReadUserSnapshot("../../ldap-users.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups, err := ReadGroupSnapshot("../../ldap-groups.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Problem{
+		{Kind: ProblemNotListed, Group: "AU Digital BDS Users", Member: "CSPUsr21"},
+		{Kind: ProblemNotListed, Group: "AU Digital BDS Users", Member: "CSPUsr22"},
+		{Kind: ProblemMemberOfMissing, Group: "AU Digital BDS Users", Member: "CSPUsr24"},
+		{Kind: ProblemUnknownGroup, Group: "AU Digital CSP Batch", Member: "CSPUsr24"},
+		{Kind: ProblemDanglingMember, Group: "AU Digital CSP Support", Member: "admin1"},
+	}
+	if got := Check(users, groups); !reflect.DeepEqual(got, want) {
+		t.Errorf("Check =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		users  map[string][]string // user -> memberOf
+		groups map[string][]string // group -> member
+		want   []Problem
+	}{
+		{
+			name:   "consistent",
+			users:  map[string][]string{"alice": {"readers"}, "bob": {"readers", "writers"}},
+			groups: map[string][]string{"readers": {"alice", "bob"}, "writers": {"bob"}},
+		},
+		{
+			name:   "one of each",
+			users:  map[string][]string{"alice": {"readers", "gone"}, "bob": nil},
+			groups: map[string][]string{"readers": {"bob", "carol"}, "empty": nil},
+			want: []Problem{
+				{Kind: ProblemEmptyGroup, Group: "empty"},
+				{Kind: ProblemUnknownGroup, Group: "gone", Member: "alice"},
+				{Kind: ProblemNotListed, Group: "readers", Member: "alice"},
+				{Kind: ProblemMemberOfMissing, Group: "readers", Member: "bob"},
+				{Kind: ProblemDanglingMember, Group: "readers", Member: "carol"},
+			},
+		},
+		{
+			name:   "nested groups",
+			users:  map[string][]string{"alice": {"team"}},
+			groups: map[string][]string{"team": {"alice"}, "department": {"team"}},
+		},
+		{
+			name:   "nesting cycle",
+			users:  map[string][]string{"alice": {"a"}},
+			groups: map[string][]string{"a": {"alice", "b"}, "b": {"a"}},
+			want: []Problem{
+				{Kind: ProblemNestingCycle, Group: "a", Member: "b > a"},
+				{Kind: ProblemNestingCycle, Group: "b", Member: "a > b"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := &UserSnapshot{Users: map[string]User{}}
+			for name, memberOf := range tt.users {
+				users.Users[name] = User{CommonName: name, MemberOf: memberOf}
+			}
+			groups := &GroupSnapshot{Groups: map[string]Group{}}
+			for name, members := range tt.groups {
+				groups.Groups[name] = Group{CommonName: name, Member: members}
+			}
+			if got := Check(users, groups); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Check =\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+// Package ldapsync reads the users and groups of the LDAP directory the
+// entitlements are granted from, so they can be exported to the snapshot
+// files or consumed directly by other components.
+package ldapsync
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/ashish246/GolangGitExample/src/credentials"
+	"gopkg.in/ldap.v3"
+)
+
+// DefaultFilter is used by Users and Groups when the filter is empty.
+const DefaultFilter = "(objectClass=*)"
+
+// DefaultPageSize stays below the 1000 entry limit of Active Directory.
+const DefaultPageSize = 500
+
+// Default ports of the ldap and ldaps schemes.
+const (
+	DefaultPort    = 389
+	DefaultTLSPort = 636
+)
+
+// Options configures a Directory.
+type Options struct {
+	// URL is the ldap:// or ldaps:// URL of the server. When empty Host
+	// and Port are dialled without TLS.
+	URL  string
+	Host string
+	Port int
+	// StartTLS upgrades an ldap:// connection to TLS before binding.
+	StartTLS bool
+	// TLS configures ldaps:// and StartTLS connections. The server name
+	// defaults to the host of the URL.
+	TLS *tls.Config
+	// PageSize is the number of entries requested per page, DefaultPageSize
+	// when zero. A negative size disables paging for servers that do not
+	// support the Simple Paged Results control.
+	PageSize int
+	// AllowInsecureBind permits sending Password over a connection that is
+	// not encrypted. Otherwise such binds fail with ErrInsecureBind.
+	AllowInsecureBind bool
+	BindDN            string
+	Password          credentials.Secret
+	// UserBaseDN and GroupBaseDN are the subtrees searched by Users and
+	// Groups.
+	UserBaseDN  string
+	GroupBaseDN string
+	// NestedGroups is how NestedGroupsOf resolves groups that are members
+	// of other groups: NestedAuto when empty, NestedInChain or NestedWalk.
+	NestedGroups string
+}
+
+// Directory searches an LDAP directory. Every search opens and binds its
+// own connection, so a Directory is safe for concurrent use.
+type Directory struct {
+	opts Options
+	// dial replaces connect when set, for tests.
+	dial func() (ldap.Client, error)
+
+	mu              sync.Mutex
+	activeDirectory *bool
+}
+
+// New returns a Directory for opts. No connection is made until the first
+// search.
+func New(opts Options) *Directory {
+	return &Directory{opts: opts}
+}
+
+// Users returns the entries below Options.UserBaseDN matching filter. Use
+// EachUser to avoid holding the whole directory in memory.
+func (d *Directory) Users(ctx context.Context, filter string) ([]User, error) {
+	var users []User
+	err := d.EachUser(ctx, filter, func(u User) error {
+		users = append(users, u)
+		return nil
+	})
+	return users, err
+}
+
+// Groups returns the entries below Options.GroupBaseDN matching filter.
+func (d *Directory) Groups(ctx context.Context, filter string) ([]Group, error) {
+	var groups []Group
+	err := d.EachGroup(ctx, filter, func(g Group) error {
+		groups = append(groups, g)
+		return nil
+	})
+	return groups, err
+}
+
+// EachUser calls fn with every entry below Options.UserBaseDN matching
+// filter as the pages of the search arrive. An error returned by fn stops
+// the search and is returned.
+func (d *Directory) EachUser(ctx context.Context, filter string, fn func(User) error) error {
+	return d.search(ctx, d.opts.UserBaseDN, filter, userAttributes, func(e *ldap.Entry) error {
+		return fn(newUser(e))
+	})
+}
+
+// EachGroup calls fn with every entry below Options.GroupBaseDN matching
+// filter as the pages of the search arrive.
+func (d *Directory) EachGroup(ctx context.Context, filter string, fn func(Group) error) error {
+	return d.search(ctx, d.opts.GroupBaseDN, filter, groupAttributes, func(e *ldap.Entry) error {
+		return fn(newGroup(e))
+	})
+}
+
+// address returns the scheme and host:port to dial.
+func (d *Directory) address() (string, string, error) {
+	if d.opts.URL == "" {
+		return "ldap", net.JoinHostPort(d.opts.Host, strconv.Itoa(d.opts.Port)), nil
+	}
+	u, err := url.Parse(d.opts.URL)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid LDAP URL %q: %w", d.opts.URL, err)
+	}
+	port := u.Port()
+	switch {
+	case u.Scheme != "ldap" && u.Scheme != "ldaps":
+		return "", "", fmt.Errorf("invalid LDAP URL %q: scheme must be ldap or ldaps", d.opts.URL)
+	case u.Hostname() == "":
+		return "", "", fmt.Errorf("invalid LDAP URL %q: no host", d.opts.URL)
+	case port == "" && u.Scheme == "ldaps":
+		port = strconv.Itoa(DefaultTLSPort)
+	case port == "":
+		port = strconv.Itoa(DefaultPort)
+	}
+	return u.Scheme, net.JoinHostPort(u.Hostname(), port), nil
+}
+
+// tlsConfig returns a copy of Options.TLS with the server name set to host
+// unless overridden.
+func (d *Directory) tlsConfig(addr string) *tls.Config {
+	config := &tls.Config{}
+	if d.opts.TLS != nil {
+		config = d.opts.TLS.Clone()
+	}
+	if config.ServerName == "" {
+		config.ServerName, _, _ = net.SplitHostPort(addr)
+	}
+	return config
+}
+
+// connect dials the directory, upgrades the connection with StartTLS when
+// asked to and binds as Options.BindDN.
+func (d *Directory) connect() (*ldap.Conn, error) {
+	scheme, addr, err := d.address()
+	if err != nil {
+		return nil, err
+	}
+	if scheme == "ldaps" && d.opts.StartTLS {
+		return nil, fmt.Errorf("StartTLS cannot be used with an ldaps URL")
+	}
+
+	var conn *ldap.Conn
+	if scheme == "ldaps" {
+		conn, err = ldap.DialTLS("tcp", addr, d.tlsConfig(addr))
+	} else {
+		conn, err = ldap.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	if d.opts.StartTLS {
+		if err := conn.StartTLS(d.tlsConfig(addr)); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to start TLS with %s: %w", addr, err)
+		}
+	}
+
+	if _, encrypted := conn.TLSConnectionState(); !encrypted && d.opts.Password != "" && !d.opts.AllowInsecureBind {
+		conn.Close()
+		return nil, fmt.Errorf("%w to %s, use ldaps or StartTLS", ErrInsecureBind, addr)
+	}
+	if err := conn.Bind(d.opts.BindDN, d.opts.Password.Reveal()); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to bind as %s: %w", d.opts.BindDN, err)
+	}
+	return conn, nil
+}
+
+// open returns a bound connection.
+func (d *Directory) open() (ldap.Client, error) {
+	if d.dial != nil {
+		return d.dial()
+	}
+	conn, err := d.connect()
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// search calls fn with the entries of the subtree baseDN matching filter.
+// Unless Options.PageSize is negative the entries are requested in pages
+// with the Simple Paged Results control, which keeps servers such as
+// Active Directory from failing the search at their size limit. The
+// connection is closed when ctx is done, which aborts the search.
+func (d *Directory) search(ctx context.Context, baseDN, filter string, attributes []string, fn func(*ldap.Entry) error) error {
+	if filter == "" {
+		filter = DefaultFilter
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	conn, err := d.open()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	var paging *ldap.ControlPaging
+	var controls []ldap.Control
+	if d.opts.PageSize >= 0 {
+		size := d.opts.PageSize
+		if size == 0 {
+			size = DefaultPageSize
+		}
+		paging = ldap.NewControlPaging(uint32(size))
+		controls = []ldap.Control{paging}
+	}
+	req := ldap.NewSearchRequest(baseDN, ldap.ScopeWholeSubtree, ldap.DerefAlways, 0, 0, false, filter, attributes, controls)
+
+	for {
+		result, err := conn.Search(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to search %s for %s: %w", baseDN, filter, err)
+		}
+		for _, entry := range result.Entries {
+			if err := fn(entry); err != nil {
+				return err
+			}
+		}
+
+		// The last page carries an empty cookie, and servers that do not
+		// support paging return everything without the control
+		if paging == nil {
+			return nil
+		}
+		next, ok := ldap.FindControl(result.Controls, ldap.ControlTypePaging).(*ldap.ControlPaging)
+		if !ok || len(next.Cookie) == 0 {
+			return nil
+		}
+		paging.SetCookie(next.Cookie)
+	}
+}
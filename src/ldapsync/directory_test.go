@@ -0,0 +1,312 @@
+package ldapsync
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gopkg.in/ldap.v3"
+)
+
+const (
+	testUserBaseDN  = "ou=Users,dc=example,dc=com"
+	testGroupBaseDN = "ou=Groups,dc=example,dc=com"
+)
+
+// fakeServer answers searches from entries held in memory, below their
+// base DN. It understands the filters the package sends: everything, a
+// modifyTimestamp lower bound and member lookups. Results are paged when
+// the request carries the paging control.
+type fakeServer struct {
+	ldap.Client
+
+	mu       sync.Mutex
+	entries  map[string][]*ldap.Entry
+	requests []*ldap.SearchRequest
+}
+
+func newFakeServer() *fakeServer {
+	return &fakeServer{entries: map[string][]*ldap.Entry{}}
+}
+
+// directory returns a Directory searching s.
+func (s *fakeServer) directory(opts Options) *Directory {
+	opts.UserBaseDN, opts.GroupBaseDN = testUserBaseDN, testGroupBaseDN
+	d := New(opts)
+	d.dial = func() (ldap.Client, error) { return s, nil }
+	return d
+}
+
+// set replaces the entry with the DN, or adds it.
+func (s *fakeServer) set(baseDN string, e *ldap.Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, old := range s.entries[baseDN] {
+		if old.DN == e.DN {
+			s.entries[baseDN][i] = e
+			return
+		}
+	}
+	s.entries[baseDN] = append(s.entries[baseDN], e)
+}
+
+// remove deletes the entry with the DN.
+func (s *fakeServer) remove(baseDN, dn string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := s.entries[baseDN][:0]
+	for _, e := range s.entries[baseDN] {
+		if e.DN != dn {
+			entries = append(entries, e)
+		}
+	}
+	s.entries[baseDN] = entries
+}
+
+func (s *fakeServer) Close() {}
+
+var (
+	modifiedSinceTerm = regexp.MustCompile(`\(modifyTimestamp>=(\d{14}Z)\)`)
+	memberTerm        = regexp.MustCompile(`\((?:member|uniqueMember)=([^)]*)\)`)
+)
+
+func (s *fakeServer) Search(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, req)
+
+	var matched []*ldap.Entry
+	for _, e := range s.entries[req.BaseDN] {
+		if matches(req.Filter, e) {
+			matched = append(matched, e)
+		}
+	}
+
+	paging, _ := ldap.FindControl(req.Controls, ldap.ControlTypePaging).(*ldap.ControlPaging)
+	if paging == nil {
+		return &ldap.SearchResult{Entries: matched}, nil
+	}
+	start := 0
+	if len(paging.Cookie) > 0 {
+		start, _ = strconv.Atoi(string(paging.Cookie))
+	}
+	end := start + int(paging.PagingSize)
+	next := &ldap.ControlPaging{}
+	if end < len(matched) {
+		next.Cookie = []byte(strconv.Itoa(end))
+	} else {
+		end = len(matched)
+	}
+	return &ldap.SearchResult{Entries: matched[start:end], Controls: []ldap.Control{next}}, nil
+}
+
+func matches(filter string, e *ldap.Entry) bool {
+	if m := modifiedSinceTerm.FindStringSubmatch(filter); m != nil {
+		if e.GetAttributeValue("modifyTimestamp") < m[1] {
+			return false
+		}
+	}
+	terms := memberTerm.FindAllStringSubmatch(filter, -1)
+	if len(terms) == 0 {
+		return true
+	}
+	members := append(e.GetAttributeValues("member"), e.GetAttributeValues("uniqueMember")...)
+	for _, term := range terms {
+		for _, member := range members {
+			if strings.EqualFold(member, term[1]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func userEntry(cn, uuid, modified string, memberOf ...string) *ldap.Entry {
+	return ldap.NewEntry("cn="+cn+","+testUserBaseDN, map[string][]string{
+		"cn":              {cn},
+		"sAMAccountName":  {strings.ToLower(cn)},
+		"entryUUID":       {uuid},
+		"modifyTimestamp": {modified},
+		"memberOf":        groupDNs(memberOf),
+	})
+}
+
+func groupEntry(cn, uuid, modified string, members ...string) *ldap.Entry {
+	dns := make([]string, len(members))
+	for i, m := range members {
+		dns[i] = "cn=" + m + "," + testUserBaseDN
+		if strings.HasPrefix(m, "group:") {
+			dns[i] = "cn=" + strings.TrimPrefix(m, "group:") + "," + testGroupBaseDN
+		}
+	}
+	return ldap.NewEntry("cn="+cn+","+testGroupBaseDN, map[string][]string{
+		"cn":              {cn},
+		"entryUUID":       {uuid},
+		"modifyTimestamp": {modified},
+		"member":          dns,
+	})
+}
+
+func groupDNs(names []string) []string {
+	dns := make([]string, len(names))
+	for i, name := range names {
+		dns[i] = "cn=" + name + "," + testGroupBaseDN
+	}
+	return dns
+}
+
+func TestSearchPaging(t *testing.T) {
+	server := newFakeServer()
+	for i := 0; i < 5; i++ {
+		server.set(testUserBaseDN, userEntry("user"+strconv.Itoa(i), "", "20200101000000Z"))
+	}
+
+	tests := []struct {
+		pageSize int
+		requests int
+	}{
+		{pageSize: 2, requests: 3},
+		{pageSize: 5, requests: 1},
+		{pageSize: 0, requests: 1},
+		{pageSize: -1, requests: 1},
+	}
+	for _, tt := range tests {
+		server.requests = nil
+		users, err := server.directory(Options{PageSize: tt.pageSize}).Users(context.Background(), "")
+		if err != nil {
+			t.Fatalf("page size %d: %v", tt.pageSize, err)
+		}
+		var names []string
+		for _, u := range users {
+			names = append(names, u.CommonName)
+		}
+		if want := []string{"user0", "user1", "user2", "user3", "user4"}; !reflect.DeepEqual(names, want) {
+			t.Errorf("page size %d: users %q, want %q", tt.pageSize, names, want)
+		}
+		if len(server.requests) != tt.requests {
+			t.Errorf("page size %d: %d requests, want %d", tt.pageSize, len(server.requests), tt.requests)
+		}
+		paged := ldap.FindControl(server.requests[0].Controls, ldap.ControlTypePaging) != nil
+		if paged != (tt.pageSize >= 0) {
+			t.Errorf("page size %d: paging control sent %v", tt.pageSize, paged)
+		}
+	}
+}
+
+func TestSearchStopsOnCallbackError(t *testing.T) {
+	server := newFakeServer()
+	for i := 0; i < 5; i++ {
+		server.set(testUserBaseDN, userEntry("user"+strconv.Itoa(i), "", "20200101000000Z"))
+	}
+	stop := errors.New("stop")
+	seen := 0
+	err := server.directory(Options{PageSize: 2}).EachUser(context.Background(), "", func(User) error {
+		seen++
+		if seen == 3 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || seen != 3 || len(server.requests) != 2 {
+		t.Errorf("EachUser = %v after %d entries and %d requests, want stop after 3 and 2", err, seen, len(server.requests))
+	}
+}
+
+func TestAddress(t *testing.T) {
+	tests := []struct {
+		opts   Options
+		scheme string
+		addr   string
+		err    bool
+	}{
+		{opts: Options{Host: "ldap.example.com", Port: 389}, scheme: "ldap", addr: "ldap.example.com:389"},
+		{opts: Options{URL: "ldap://ldap.example.com"}, scheme: "ldap", addr: "ldap.example.com:389"},
+		{opts: Options{URL: "ldaps://ldap.example.com"}, scheme: "ldaps", addr: "ldap.example.com:636"},
+		{opts: Options{URL: "ldaps://ldap.example.com:3269"}, scheme: "ldaps", addr: "ldap.example.com:3269"},
+		{opts: Options{URL: "ldap://[::1]:10389"}, scheme: "ldap", addr: "[::1]:10389"},
+		{opts: Options{URL: "http://ldap.example.com"}, err: true},
+		{opts: Options{URL: "ldap://"}, err: true},
+		{opts: Options{URL: "ldap://%zz"}, err: true},
+	}
+	for _, tt := range tests {
+		scheme, addr, err := New(tt.opts).address()
+		if (err != nil) != tt.err {
+			t.Errorf("address of %+v error = %v, want error %v", tt.opts, err, tt.err)
+			continue
+		}
+		if scheme != tt.scheme || addr != tt.addr {
+			t.Errorf("address of %+v = %s %s, want %s %s", tt.opts, scheme, addr, tt.scheme, tt.addr)
+		}
+	}
+}
+
+func TestConnectRefusesInsecureBind(t *testing.T) {
+	// The listener hangs up at once, so a bind that is attempted fails
+	// without waiting for an answer
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	url := "ldap://" + l.Addr().String()
+
+	tests := []struct {
+		name     string
+		opts     Options
+		insecure bool
+	}{
+		{name: "password", opts: Options{URL: url, BindDN: "cn=admin", Password: "s3cret"}, insecure: true},
+		{name: "allowed", opts: Options{URL: url, BindDN: "cn=admin", Password: "s3cret", AllowInsecureBind: true}},
+		{name: "anonymous", opts: Options{URL: url}},
+	}
+	for _, tt := range tests {
+		_, err := New(tt.opts).connect()
+		if err == nil {
+			t.Errorf("%s: connect succeeded against a server that hangs up", tt.name)
+			continue
+		}
+		if errors.Is(err, ErrInsecureBind) != tt.insecure {
+			t.Errorf("%s: connect error = %v, want ErrInsecureBind %v", tt.name, err, tt.insecure)
+		}
+		if strings.Contains(err.Error(), "s3cret") {
+			t.Errorf("%s: error leaks the password: %v", tt.name, err)
+		}
+	}
+}
+
+func TestGeneralizedTime(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"20170925092902Z", time.Date(2017, 9, 25, 9, 29, 2, 0, time.UTC)},
+		{"20170925092902.0Z", time.Date(2017, 9, 25, 9, 29, 2, 0, time.UTC)},
+		{"20170925092902.123Z", time.Date(2017, 9, 25, 9, 29, 2, 0, time.UTC)},
+		{"20170925112902+0200", time.Date(2017, 9, 25, 9, 29, 2, 0, time.UTC)},
+		{"20170925112902.0+0200", time.Date(2017, 9, 25, 9, 29, 2, 0, time.UTC)},
+		{"20170925092902.0", time.Time{}},
+		{"2017-09-25T09:29:02Z", time.Time{}},
+		{"", time.Time{}},
+	}
+	for _, tt := range tests {
+		if got := generalizedTime(tt.value); !got.Equal(tt.want) {
+			t.Errorf("generalizedTime(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
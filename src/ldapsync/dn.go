@@ -0,0 +1,64 @@
+package ldapsync
+
+import (
+	"sort"
+	"strings"
+
+	"gopkg.in/ldap.v3"
+)
+
+// Member is a value of the member or uniqueMember attribute of a group.
+type Member struct {
+	DN string
+	// Name is the value of the first RDN of DN, the common name of the
+	// member, or DN itself when it is not a valid DN.
+	Name string
+}
+
+// parseMember parses a member DN as defined by RFC 4514, so escaped
+// characters such as the comma in "cn=1\2C lenovo,ou=Users" are decoded.
+// The optional "#'0101'B" unique identifier of a uniqueMember value is
+// dropped.
+func parseMember(value string) Member {
+	dn := value
+	if i := strings.LastIndex(dn, "#'"); i >= 0 && strings.HasSuffix(dn, "'B") {
+		dn = dn[:i]
+	}
+	m := Member{DN: dn, Name: value}
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil || len(parsed.RDNs) == 0 || len(parsed.RDNs[0].Attributes) == 0 {
+		return m
+	}
+	m.Name = parsed.RDNs[0].Attributes[0].Value
+	return m
+}
+
+// commonNames returns the names of the members with the DNs, sorted since
+// servers return the values of an attribute in no particular order.
+func commonNames(dns []string) []string {
+	names := make([]string, 0, len(dns))
+	for _, dn := range dns {
+		names = append(names, parseMember(dn).Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// normalizeDN returns a form of dn that is equal for DNs matching each
+// other case-insensitively, whatever their escaping and spacing.
+func normalizeDN(dn string) (string, bool) {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil {
+		return "", false
+	}
+	rdns := make([]string, len(parsed.RDNs))
+	for i, rdn := range parsed.RDNs {
+		attrs := make([]string, len(rdn.Attributes))
+		for j, attr := range rdn.Attributes {
+			attrs[j] = strings.ToLower(attr.Type) + "=" + strings.ToLower(attr.Value)
+		}
+		sort.Strings(attrs)
+		rdns[i] = strings.Join(attrs, "+")
+	}
+	return strings.Join(rdns, ","), true
+}
@@ -0,0 +1,88 @@
+package ldapsync
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMember(t *testing.T) {
+	tests := []struct {
+		value string
+		want  Member
+	}{
+		{
+			value: "cn=CSPUsr21,ou=Users,dc=example,dc=com",
+			want:  Member{DN: "cn=CSPUsr21,ou=Users,dc=example,dc=com", Name: "CSPUsr21"},
+		},
+		{
+			value: `cn=1\2C lenovo,ou=Users,dc=example,dc=com`,
+			want:  Member{DN: `cn=1\2C lenovo,ou=Users,dc=example,dc=com`, Name: "1, lenovo"},
+		},
+		{
+			value: `cn=1\, lenovo,ou=Users,dc=example,dc=com`,
+			want:  Member{DN: `cn=1\, lenovo,ou=Users,dc=example,dc=com`, Name: "1, lenovo"},
+		},
+		{
+			value: "cn=admin1+uid=a1,ou=Users,dc=example,dc=com",
+			want:  Member{DN: "cn=admin1+uid=a1,ou=Users,dc=example,dc=com", Name: "admin1"},
+		},
+		{
+			value: "cn=CSPUsr21,ou=Users,dc=example,dc=com#'0101'B",
+			want:  Member{DN: "cn=CSPUsr21,ou=Users,dc=example,dc=com", Name: "CSPUsr21"},
+		},
+		{
+			value: "CSPUsr21",
+			want:  Member{DN: "CSPUsr21", Name: "CSPUsr21"},
+		},
+		{
+			value: "cn=1\\zz,ou=Users",
+			want:  Member{DN: "cn=1\\zz,ou=Users", Name: "cn=1\\zz,ou=Users"},
+		},
+	}
+	for _, tt := range tests {
+		if got := parseMember(tt.value); got != tt.want {
+			t.Errorf("parseMember(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestCommonNames(t *testing.T) {
+	got := commonNames([]string{
+		"cn=CSPUsr22,ou=Users,dc=example,dc=com",
+		`cn=1\2C lenovo,ou=Users,dc=example,dc=com`,
+		"cn=CSPUsr21,ou=Users,dc=example,dc=com",
+	})
+	want := []string{"1, lenovo", "CSPUsr21", "CSPUsr22"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("commonNames = %q, want %q", got, want)
+	}
+}
+
+func TestNormalizeDN(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"cn=CSPUsr21,ou=Users,dc=example,dc=com", "CN=cspusr21, OU=users, DC=Example, DC=com", true},
+		{`cn=1\2C lenovo,ou=Users`, `cn=1\, lenovo,ou=Users`, true},
+		{"cn=a+uid=b,ou=Users", "uid=b+cn=a,ou=Users", true},
+		{"cn=CSPUsr21,ou=Users", "cn=CSPUsr22,ou=Users", false},
+		{"cn=CSPUsr21,ou=Users", "cn=CSPUsr21,ou=Groups", false},
+	}
+	for _, tt := range tests {
+		a, ok := normalizeDN(tt.a)
+		if !ok {
+			t.Fatalf("normalizeDN(%q) failed", tt.a)
+		}
+		b, ok := normalizeDN(tt.b)
+		if !ok {
+			t.Fatalf("normalizeDN(%q) failed", tt.b)
+		}
+		if (a == b) != tt.same {
+			t.Errorf("normalizeDN(%q) = %q, normalizeDN(%q) = %q, want equal %v", tt.a, a, tt.b, b, tt.same)
+		}
+	}
+	if _, ok := normalizeDN("cn=1\\zz,ou=Users"); ok {
+		t.Error("normalizeDN accepted an invalid DN")
+	}
+}
@@ -0,0 +1,140 @@
+package ldapsync
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/ldap.v3"
+)
+
+// operationalAttributes are not returned by "*" and have to be asked for.
+var operationalAttributes = []string{
+	"creatorsName", "createTimestamp", "modifiersName", "modifyTimestamp",
+	"entryDN", "entryUUID", "structuralObjectClass", "subschemaSubentry",
+	"hasSubordinates",
+}
+
+var (
+	userAttributes  = append([]string{"*", "memberOf"}, operationalAttributes...)
+	groupAttributes = append([]string{"*"}, operationalAttributes...)
+)
+
+// User is a person in the directory. The fields are declared in the order
+// of their JSON names, which are those of ldap-users.json.
+type User struct {
+	SAMAccountName  string    `json:"SAMAccountName"`
+	CommonName      string    `json:"commonName"`
+	CreateTimestamp time.Time `json:"createTimestamp"`
+	CreatorsName    string    `json:"creatorsName"`
+	EntryDN         string    `json:"entryDN"`
+	EntryUUID       string    `json:"entryUUID"`
+	HasSubordinates bool      `json:"hasSubordinates"`
+	// MemberOf holds the common names of the groups the user belongs to.
+	// It is left out of the snapshot for users without groups.
+	MemberOf              []string  `json:"memberOf,omitempty"`
+	ModifiersName         string    `json:"modifiersName"`
+	ModifyTimestamp       time.Time `json:"modifyTimestamp"`
+	ObjectClass           []string  `json:"objectClass"`
+	StructuralObjectClass string    `json:"structuralObjectClass"`
+	SubschemaSubentry     string    `json:"subschemaSubentry"`
+	Surname               string    `json:"surname"`
+	// VersionID counts the changes of the entry seen by the snapshots. The
+	// directory does not provide it, so it is zero in search results.
+	VersionID int `json:"versionId"`
+}
+
+// Group is a group of users in the directory. The fields are declared in
+// the order of their JSON names, which are those of ldap-groups.json.
+type Group struct {
+	CommonName      string    `json:"commonName"`
+	CreateTimestamp time.Time `json:"createTimestamp"`
+	CreatorsName    string    `json:"creatorsName"`
+	EntryDN         string    `json:"entryDN"`
+	EntryUUID       string    `json:"entryUUID"`
+	HasSubordinates bool      `json:"hasSubordinates"`
+	// Member holds the common names of the members of the group.
+	Member []string `json:"member"`
+	// Members are the parsed member DNs of a group read from the
+	// directory. They are not kept in the snapshot.
+	Members               []Member  `json:"-"`
+	ModifiersName         string    `json:"modifiersName"`
+	ModifyTimestamp       time.Time `json:"modifyTimestamp"`
+	ObjectClass           []string  `json:"objectClass"`
+	StructuralObjectClass string    `json:"structuralObjectClass"`
+	SubschemaSubentry     string    `json:"subschemaSubentry"`
+	// VersionID counts the changes of the entry seen by the snapshots.
+	VersionID int `json:"versionId"`
+}
+
+// entryDN prefers the entryDN operational attribute over the DN of the
+// search result, which the server may have normalised differently.
+func entryDN(e *ldap.Entry) string {
+	if dn := e.GetAttributeValue("entryDN"); dn != "" {
+		return dn
+	}
+	return e.DN
+}
+
+func newUser(e *ldap.Entry) User {
+	return User{
+		SAMAccountName:        e.GetAttributeValue("sAMAccountName"),
+		CommonName:            e.GetAttributeValue("cn"),
+		CreateTimestamp:       generalizedTime(e.GetAttributeValue("createTimestamp")),
+		CreatorsName:          e.GetAttributeValue("creatorsName"),
+		EntryDN:               entryDN(e),
+		EntryUUID:             e.GetAttributeValue("entryUUID"),
+		HasSubordinates:       strings.EqualFold(e.GetAttributeValue("hasSubordinates"), "TRUE"),
+		MemberOf:              commonNames(e.GetAttributeValues("memberOf")),
+		ModifiersName:         e.GetAttributeValue("modifiersName"),
+		ModifyTimestamp:       generalizedTime(e.GetAttributeValue("modifyTimestamp")),
+		ObjectClass:           e.GetAttributeValues("objectClass"),
+		StructuralObjectClass: e.GetAttributeValue("structuralObjectClass"),
+		SubschemaSubentry:     e.GetAttributeValue("subschemaSubentry"),
+		Surname:               e.GetAttributeValue("sn"),
+	}
+}
+
+func newGroup(e *ldap.Entry) Group {
+	// groupOfUniqueNames lists its members in uniqueMember, groupOfNames
+	// and Active Directory groups in member
+	values := append(e.GetAttributeValues("uniqueMember"), e.GetAttributeValues("member")...)
+	members := make([]Member, len(values))
+	for i, value := range values {
+		members[i] = parseMember(value)
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].Name < members[j].Name })
+	return Group{
+		CommonName:            e.GetAttributeValue("cn"),
+		CreateTimestamp:       generalizedTime(e.GetAttributeValue("createTimestamp")),
+		CreatorsName:          e.GetAttributeValue("creatorsName"),
+		EntryDN:               entryDN(e),
+		EntryUUID:             e.GetAttributeValue("entryUUID"),
+		HasSubordinates:       strings.EqualFold(e.GetAttributeValue("hasSubordinates"), "TRUE"),
+		Member:                commonNames(values),
+		Members:               members,
+		ModifiersName:         e.GetAttributeValue("modifiersName"),
+		ModifyTimestamp:       generalizedTime(e.GetAttributeValue("modifyTimestamp")),
+		ObjectClass:           e.GetAttributeValues("objectClass"),
+		StructuralObjectClass: e.GetAttributeValue("structuralObjectClass"),
+		SubschemaSubentry:     e.GetAttributeValue("subschemaSubentry"),
+	}
+}
+
+// generalizedTime parses an LDAP GeneralizedTime such as 20170925092902Z
+// or 20170925092902.0Z as used by Active Directory. Values that do not
+// parse yield the zero time.
+func generalizedTime(value string) time.Time {
+	if i := strings.IndexByte(value, '.'); i >= 0 {
+		end := strings.IndexAny(value[i:], "Z+-")
+		if end < 0 {
+			return time.Time{}
+		}
+		value = value[:i] + value[i+end:]
+	}
+	t, err := time.Parse("20060102150405Z0700", value)
+	if err != nil {
+		return time.Time{}
+	}
+	return t.UTC()
+}
@@ -0,0 +1,160 @@
+package ldapsync
+
+import (
+	"sort"
+	"strings"
+)
+
+// Index answers membership questions from the snapshots without a live
+// directory. Group membership is taken from the member lists of the
+// groups, which the directory maintains; memberOf is only a back-link.
+type Index struct {
+	users        map[string]User
+	bySAMAccount map[string]string
+	byDN         map[string]string
+	groups       map[string]Group
+	groupByDN    map[string]string
+	byUUID       map[string]string
+	groupsOf     map[string][]string
+}
+
+// LoadIndex reads the user and group snapshots and indexes them.
+func LoadIndex(usersFile, groupsFile string) (*Index, error) {
+	users, err := ReadUserSnapshot(usersFile)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := ReadGroupSnapshot(groupsFile)
+	if err != nil {
+		return nil, err
+	}
+	return NewIndex(users, groups), nil
+}
+
+// NewIndex indexes the snapshots. Later changes to them are not seen.
+func NewIndex(users *UserSnapshot, groups *GroupSnapshot) *Index {
+	x := &Index{
+		users:        make(map[string]User, len(users.Users)),
+		bySAMAccount: make(map[string]string, len(users.Users)),
+		byDN:         make(map[string]string, len(users.Users)),
+		groups:       make(map[string]Group, len(groups.Groups)),
+		groupByDN:    make(map[string]string, len(groups.Groups)),
+		byUUID:       make(map[string]string, len(groups.Groups)),
+		groupsOf:     memberships(groups),
+	}
+	for name, u := range users.Users {
+		x.users[name] = u
+		if u.SAMAccountName != "" {
+			x.bySAMAccount[strings.ToLower(u.SAMAccountName)] = name
+		}
+		if dn, ok := normalizeDN(u.EntryDN); ok {
+			x.byDN[dn] = name
+		}
+	}
+	for name, g := range groups.Groups {
+		x.groups[name] = g
+		if g.EntryUUID != "" {
+			x.byUUID[strings.ToLower(g.EntryUUID)] = name
+		}
+		if dn, ok := normalizeDN(g.EntryDN); ok {
+			x.groupByDN[dn] = name
+		}
+	}
+	for _, names := range x.groupsOf {
+		sort.Strings(names)
+	}
+	return x
+}
+
+// User returns the user with the common name.
+func (x *Index) User(commonName string) (User, bool) {
+	u, ok := x.users[commonName]
+	return u, ok
+}
+
+// UserBySAMAccountName returns the user with the account name, which is
+// matched case-insensitively like Active Directory does.
+func (x *Index) UserBySAMAccountName(name string) (User, bool) {
+	commonName, ok := x.bySAMAccount[strings.ToLower(name)]
+	if !ok {
+		return User{}, false
+	}
+	return x.User(commonName)
+}
+
+// UserByDN returns the user with the entryDN. DNs are compared after
+// parsing, so escaping, spacing and case do not matter.
+func (x *Index) UserByDN(dn string) (User, bool) {
+	key, ok := normalizeDN(dn)
+	if !ok {
+		return User{}, false
+	}
+	commonName, ok := x.byDN[key]
+	if !ok {
+		return User{}, false
+	}
+	return x.User(commonName)
+}
+
+// Group returns the group with the common name.
+func (x *Index) Group(commonName string) (Group, bool) {
+	g, ok := x.groups[commonName]
+	return g, ok
+}
+
+// GroupByEntryUUID returns the group with the entryUUID.
+func (x *Index) GroupByEntryUUID(uuid string) (Group, bool) {
+	commonName, ok := x.byUUID[strings.ToLower(uuid)]
+	if !ok {
+		return Group{}, false
+	}
+	return x.Group(commonName)
+}
+
+// GroupsOf returns the groups listing the user or group with the common
+// name as a member, sorted by name.
+func (x *Index) GroupsOf(commonName string) []Group {
+	var groups []Group
+	for _, name := range x.groupsOf[commonName] {
+		groups = append(groups, x.groups[name])
+	}
+	return groups
+}
+
+// MembersOf returns the users listed as members of the group, sorted by
+// common name. Members that are not users of the snapshot are skipped, see
+// NestedMembersOf for the users of member groups.
+func (x *Index) MembersOf(group string) []User {
+	users, _ := x.ResolveMembers(x.groups[group])
+	return users
+}
+
+// ResolveMembers returns the users that are members of the group, sorted
+// by common name, and the members that are not users of the snapshot,
+// such as nested groups.
+// Members read from the directory are resolved by DN; those of a snapshot,
+// which only keeps their names, by common name.
+func (x *Index) ResolveMembers(g Group) ([]User, []Member) {
+	members := g.Members
+	if members == nil {
+		members = make([]Member, len(g.Member))
+		for i, name := range g.Member {
+			members[i] = Member{Name: name}
+		}
+	}
+	var users []User
+	var unresolved []Member
+	for _, m := range members {
+		u, ok := x.users[m.Name]
+		if m.DN != "" {
+			u, ok = x.UserByDN(m.DN)
+		}
+		if !ok {
+			unresolved = append(unresolved, m)
+			continue
+		}
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].CommonName < users[j].CommonName })
+	return users, unresolved
+}
@@ -0,0 +1,109 @@
+package ldapsync
+
+import (
+	"reflect"
+	"testing"
+)
+
+func loadShippedIndex(t *testing.T) *Index {
+	x, err := LoadIndex("../../ldap-users.json", "../../ldap-groups.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return x
+}
+
+func TestIndexUsers(t *testing.T) {
+	x := loadShippedIndex(t)
+	tests := []struct {
+		name   string
+		lookup func() (User, bool)
+		want   string
+	}{
+		{"common name", func() (User, bool) { return x.User("1, lenovo") }, "1, lenovo"},
+		{"account", func() (User, bool) { return x.UserBySAMAccountName("dunnn1admin") }, "Dunn, Nigel (Admin)"},
+		{"account in another case", func() (User, bool) { return x.UserBySAMAccountName("DUNNN1ADMIN") }, "Dunn, Nigel (Admin)"},
+		{"escaped DN", func() (User, bool) {
+			return x.UserByDN(`cn=Dunn\2C Nigel (Admin),ou=Users,ou=AU,dc=globaltest,dc=anz,dc=com`)
+		}, "Dunn, Nigel (Admin)"},
+		{"DN spelled differently", func() (User, bool) {
+			return x.UserByDN(`CN=Dunn\, Nigel (Admin), OU=Users, OU=AU, DC=globaltest, DC=anz, DC=com`)
+		}, "Dunn, Nigel (Admin)"},
+		{"unknown common name", func() (User, bool) { return x.User("admin1") }, ""},
+		{"unknown account", func() (User, bool) { return x.UserBySAMAccountName("admin1") }, ""},
+		{"invalid DN", func() (User, bool) { return x.UserByDN(`cn=1\zz`) }, ""},
+	}
+	for _, tt := range tests {
+		u, ok := tt.lookup()
+		if ok != (tt.want != "") || u.CommonName != tt.want {
+			t.Errorf("%s: found %q, %v, want %q", tt.name, u.CommonName, ok, tt.want)
+		}
+	}
+}
+
+func TestIndexGroupsOf(t *testing.T) {
+	x := loadShippedIndex(t)
+	tests := []struct {
+		user string
+		want []string
+	}{
+		// memberOf claims AU Digital BDS Users too, which does not list them
+		{"CSPUsr22", []string{"AU Digital CSP Support"}},
+		{"CSPUsr24", []string{"AU Digital BDS Users"}},
+		{"DAZ_superuser", []string{"AU Digital BD Read", "AU Digital BD Write", "AU Digital DAZ Read"}},
+		{"CI", nil},
+		{"nobody", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, g := range x.GroupsOf(tt.user) {
+			got = append(got, g.CommonName)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GroupsOf(%q) = %q, want %q", tt.user, got, tt.want)
+		}
+	}
+}
+
+func TestIndexMembersOf(t *testing.T) {
+	x := loadShippedIndex(t)
+	tests := []struct {
+		group      string
+		want       []string
+		unresolved []string
+	}{
+		{"AU Digital CSP Support", []string{"CSPUsr2", "CSPUsr22", "CSPUsr3", "sbosadmin"}, []string{"admin1"}},
+		{"AU Digital KYC Write (FP)", []string{"Manager"}, nil},
+		{"nothing", nil, nil},
+	}
+	for _, tt := range tests {
+		g, _ := x.Group(tt.group)
+		users, unresolved := x.ResolveMembers(g)
+		var got, missing []string
+		for _, u := range users {
+			got = append(got, u.CommonName)
+		}
+		for _, m := range unresolved {
+			missing = append(missing, m.Name)
+		}
+		if !reflect.DeepEqual(got, tt.want) || !reflect.DeepEqual(missing, tt.unresolved) {
+			t.Errorf("ResolveMembers(%q) = %q, %q, want %q, %q", tt.group, got, missing, tt.want, tt.unresolved)
+		}
+		if members := x.MembersOf(tt.group); len(members) != len(tt.want) {
+			t.Errorf("MembersOf(%q) returned %d users, want %d", tt.group, len(members), len(tt.want))
+		}
+	}
+}
+
+func TestIndexGroupByEntryUUID(t *testing.T) {
+	x := loadShippedIndex(t)
+	for _, uuid := range []string{"b6938b34-361f-1037-8d88-65eead392ae4", "B6938B34-361F-1037-8D88-65EEAD392AE4"} {
+		g, ok := x.GroupByEntryUUID(uuid)
+		if !ok || g.CommonName != "AU Digital CSP Support" {
+			t.Errorf("GroupByEntryUUID(%s) = %q, %v", uuid, g.CommonName, ok)
+		}
+	}
+	if _, ok := x.GroupByEntryUUID("00000000-0000-0000-0000-000000000000"); ok {
+		t.Error("GroupByEntryUUID found an unknown uuid")
+	}
+}
@@ -0,0 +1,221 @@
+package ldapsync
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/ldap.v3"
+)
+
+// Ways of resolving nested group membership, see Options.NestedGroups.
+const (
+	// NestedAuto uses NestedInChain when the server is Active Directory
+	// and NestedWalk otherwise.
+	NestedAuto = "auto"
+	// NestedInChain leaves the walk to the server with the
+	// LDAP_MATCHING_RULE_IN_CHAIN matching rule, in a single search.
+	NestedInChain = "in_chain"
+	// NestedWalk searches the groups listing each level of members until
+	// no new group is found.
+	NestedWalk = "walk"
+)
+
+// MatchingRuleInChain is the OID of LDAP_MATCHING_RULE_IN_CHAIN, the
+// Active Directory matching rule that follows member links transitively.
+const MatchingRuleInChain = "1.2.840.113556.1.4.1941"
+
+// activeDirectoryCapability is advertised in the supportedCapabilities of
+// the root DSE of Active Directory domain controllers.
+const activeDirectoryCapability = "1.2.840.113556.1.4.800"
+
+// Membership is a group a user or group belongs to, directly or through
+// nested groups.
+type Membership struct {
+	Group Group
+	// Via lists the groups between the member and Group, the one listing
+	// the member first. It is empty for direct memberships.
+	Via []string
+}
+
+// NestedGroupsOf returns the groups the entry with the DN is a member of,
+// directly or through groups that are members of other groups, sorted by
+// common name. Options.NestedGroups selects how the directory is asked.
+func (d *Directory) NestedGroupsOf(ctx context.Context, dn string) ([]Group, error) {
+	inChain, err := d.inChain(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var groups []Group
+	if inChain {
+		filter := fmt.Sprintf("(member:%s:=%s)", MatchingRuleInChain, ldap.EscapeFilter(dn))
+		groups, err = d.Groups(ctx, filter)
+	} else {
+		groups, err = d.walkGroups(ctx, dn)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].CommonName < groups[j].CommonName })
+	return groups, nil
+}
+
+// inChain reports whether NestedGroupsOf can use MatchingRuleInChain. The
+// root DSE is only read once.
+func (d *Directory) inChain(ctx context.Context) (bool, error) {
+	switch d.opts.NestedGroups {
+	case NestedInChain:
+		return true, nil
+	case NestedWalk:
+		return false, nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.activeDirectory == nil {
+		capabilities, err := d.rootDSE(ctx, "supportedCapabilities")
+		if err != nil {
+			return false, err
+		}
+		ad := contains(capabilities, activeDirectoryCapability)
+		d.activeDirectory = &ad
+	}
+	return *d.activeDirectory, nil
+}
+
+// rootDSE returns the values of the attribute of the root DSE, the entry
+// describing the server.
+func (d *Directory) rootDSE(ctx context.Context, attribute string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	conn, err := d.open()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	req := ldap.NewSearchRequest("", ldap.ScopeBaseObject, ldap.NeverDerefAliases, 0, 0, false, DefaultFilter, []string{attribute}, nil)
+	result, err := conn.Search(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the root DSE: %w", err)
+	}
+	if len(result.Entries) == 0 {
+		return nil, nil
+	}
+	return result.Entries[0].GetAttributeValues(attribute), nil
+}
+
+// walkGroups searches the groups listing dn, then the groups listing
+// those, one search per level. Groups already seen are not searched
+// again, which ends the walk on membership cycles.
+func (d *Directory) walkGroups(ctx context.Context, dn string) ([]Group, error) {
+	var groups []Group
+	seen := map[string]bool{}
+	if key, ok := normalizeDN(dn); ok {
+		seen[key] = true
+	}
+	level := []string{dn}
+	for len(level) > 0 {
+		var terms []string
+		for _, member := range level {
+			escaped := ldap.EscapeFilter(member)
+			terms = append(terms, "(member="+escaped+")", "(uniqueMember="+escaped+")")
+		}
+		var next []string
+		err := d.EachGroup(ctx, "(|"+strings.Join(terms, "")+")", func(g Group) error {
+			key, ok := normalizeDN(g.EntryDN)
+			if !ok {
+				key = g.EntryDN
+			}
+			if seen[key] {
+				return nil
+			}
+			seen[key] = true
+			groups = append(groups, g)
+			next = append(next, g.EntryDN)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		level = next
+	}
+	return groups, nil
+}
+
+// NestedGroupsOf returns the groups the user or group with the common
+// name belongs to, directly or through nested groups, sorted by common
+// name. Each group is reached through the shortest chain of groups, and
+// a group nested in itself is listed with the cycle as Via.
+func (x *Index) NestedGroupsOf(commonName string) []Membership {
+	type step struct {
+		name string
+		via  []string
+	}
+	var memberships []Membership
+	seen := map[string]bool{}
+	queue := []step{{name: commonName}}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		for _, name := range x.groupsOf[s.name] {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			memberships = append(memberships, Membership{Group: x.groups[name], Via: s.via})
+			via := append(append([]string(nil), s.via...), name)
+			queue = append(queue, step{name: name, via: via})
+		}
+	}
+	sort.Slice(memberships, func(i, j int) bool {
+		return memberships[i].Group.CommonName < memberships[j].Group.CommonName
+	})
+	return memberships
+}
+
+// NestedMembersOf returns the users that are members of the group,
+// directly or through member groups, sorted by common name. Member groups
+// already expanded are skipped, so membership cycles end the walk.
+func (x *Index) NestedMembersOf(group string) []User {
+	var users []User
+	seenUsers := map[string]bool{}
+	seen := map[string]bool{group: true}
+	queue := []string{group}
+	for len(queue) > 0 {
+		g := x.groups[queue[0]]
+		queue = queue[1:]
+		members, unresolved := x.ResolveMembers(g)
+		for _, u := range members {
+			if !seenUsers[u.CommonName] {
+				seenUsers[u.CommonName] = true
+				users = append(users, u)
+			}
+		}
+		for _, m := range unresolved {
+			name, ok := x.memberGroup(m)
+			if ok && !seen[name] {
+				seen[name] = true
+				queue = append(queue, name)
+			}
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].CommonName < users[j].CommonName })
+	return users
+}
+
+// memberGroup returns the common name of the group a member that is not a
+// user refers to.
+func (x *Index) memberGroup(m Member) (string, bool) {
+	if m.DN == "" {
+		_, ok := x.groups[m.Name]
+		return m.Name, ok
+	}
+	key, ok := normalizeDN(m.DN)
+	if !ok {
+		return "", false
+	}
+	name, ok := x.groupByDN[key]
+	return name, ok
+}
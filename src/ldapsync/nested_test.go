@@ -0,0 +1,115 @@
+package ldapsync
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// nestedSnapshots returns alice in team, nested in department, nested in
+// division, and the groups a and b listing each other.
+func nestedSnapshots() (*UserSnapshot, *GroupSnapshot) {
+	users := &UserSnapshot{Users: map[string]User{
+		"alice": {CommonName: "alice", MemberOf: []string{"team"}},
+		"bob":   {CommonName: "bob", MemberOf: []string{"a"}},
+	}}
+	groups := &GroupSnapshot{Groups: map[string]Group{
+		"team":       {CommonName: "team", Member: []string{"alice"}},
+		"department": {CommonName: "department", Member: []string{"team"}},
+		"division":   {CommonName: "division", Member: []string{"department"}},
+		"a":          {CommonName: "a", Member: []string{"b", "bob"}},
+		"b":          {CommonName: "b", Member: []string{"a"}},
+	}}
+	return users, groups
+}
+
+func TestIndexNestedGroupsOf(t *testing.T) {
+	x := NewIndex(nestedSnapshots())
+	tests := []struct {
+		name string
+		want []Membership
+	}{
+		{"alice", []Membership{
+			{Group: Group{CommonName: "department", Member: []string{"team"}}, Via: []string{"team"}},
+			{Group: Group{CommonName: "division", Member: []string{"department"}}, Via: []string{"team", "department"}},
+			{Group: Group{CommonName: "team", Member: []string{"alice"}}},
+		}},
+		{"bob", []Membership{
+			{Group: Group{CommonName: "a", Member: []string{"b", "bob"}}},
+			{Group: Group{CommonName: "b", Member: []string{"a"}}, Via: []string{"a"}},
+		}},
+		// a is nested in itself through b
+		{"a", []Membership{
+			{Group: Group{CommonName: "a", Member: []string{"b", "bob"}}, Via: []string{"b"}},
+			{Group: Group{CommonName: "b", Member: []string{"a"}}},
+		}},
+		{"nobody", nil},
+	}
+	for _, tt := range tests {
+		if got := x.NestedGroupsOf(tt.name); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("NestedGroupsOf(%q) =\n%+v\nwant\n%+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIndexNestedMembersOf(t *testing.T) {
+	x := NewIndex(nestedSnapshots())
+	tests := []struct {
+		group string
+		want  []string
+	}{
+		{"division", []string{"alice"}},
+		{"department", []string{"alice"}},
+		{"a", []string{"bob"}},
+		{"b", []string{"bob"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, u := range x.NestedMembersOf(tt.group) {
+			got = append(got, u.CommonName)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("NestedMembersOf(%q) = %q, want %q", tt.group, got, tt.want)
+		}
+	}
+}
+
+func TestDirectoryNestedGroupsWalk(t *testing.T) {
+	server := newFakeServer()
+	server.set(testUserBaseDN, userEntry("alice", "u1", "20200101000000Z", "team"))
+	server.set(testUserBaseDN, userEntry("bob", "u2", "20200101000000Z", "a"))
+	server.set(testGroupBaseDN, groupEntry("team", "g1", "20200101000000Z", "alice"))
+	server.set(testGroupBaseDN, groupEntry("department", "g2", "20200101000000Z", "group:team"))
+	server.set(testGroupBaseDN, groupEntry("division", "g3", "20200101000000Z", "group:department"))
+	server.set(testGroupBaseDN, groupEntry("a", "g4", "20200101000000Z", "bob", "group:b"))
+	server.set(testGroupBaseDN, groupEntry("b", "g5", "20200101000000Z", "group:a"))
+	d := server.directory(Options{NestedGroups: NestedWalk})
+
+	tests := []struct {
+		dn       string
+		want     []string
+		searches int
+	}{
+		// One search per level and a last one finding nothing new
+		{"cn=alice," + testUserBaseDN, []string{"department", "division", "team"}, 4},
+		// The cycle ends once both groups were seen
+		{"cn=bob," + testUserBaseDN, []string{"a", "b"}, 3},
+	}
+	for _, tt := range tests {
+		server.requests = nil
+		groups, err := d.NestedGroupsOf(context.Background(), tt.dn)
+		if err != nil {
+			t.Fatalf("NestedGroupsOf(%s): %v", tt.dn, err)
+		}
+		var got []string
+		for _, g := range groups {
+			got = append(got, g.CommonName)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("NestedGroupsOf(%s) = %q, want %q", tt.dn, got, tt.want)
+		}
+		if len(server.requests) != tt.searches {
+			t.Errorf("NestedGroupsOf(%s) searched %d times, want %d", tt.dn, len(server.requests), tt.searches)
+		}
+	}
+}
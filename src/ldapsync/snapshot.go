@@ -0,0 +1,103 @@
+package ldapsync
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// UserSnapshot is the content of ldap-users.json: the users of the
+// directory keyed by common name.
+type UserSnapshot struct {
+	// LastModified is the newest modifyTimestamp of the entries, the lower
+	// bound of the next incremental sync.
+	LastModified time.Time       `json:"lastmodified"`
+	Type         string          `json:"type"`
+	Users        map[string]User `json:"users"`
+}
+
+// GroupSnapshot is the content of ldap-groups.json: the groups of the
+// directory keyed by common name.
+type GroupSnapshot struct {
+	LastModified time.Time        `json:"lastmodified"`
+	Type         string           `json:"type"`
+	Groups       map[string]Group `json:"groups"`
+}
+
+// ReadUserSnapshot reads file. A missing file yields an empty snapshot.
+func ReadUserSnapshot(file string) (*UserSnapshot, error) {
+	s := &UserSnapshot{Users: map[string]User{}}
+	if err := readJSON(file, s); err != nil {
+		return nil, err
+	}
+	if s.Users == nil {
+		s.Users = map[string]User{}
+	}
+	return s, nil
+}
+
+// ReadGroupSnapshot reads file. A missing file yields an empty snapshot.
+func ReadGroupSnapshot(file string) (*GroupSnapshot, error) {
+	s := &GroupSnapshot{Groups: map[string]Group{}}
+	if err := readJSON(file, s); err != nil {
+		return nil, err
+	}
+	if s.Groups == nil {
+		s.Groups = map[string]Group{}
+	}
+	return s, nil
+}
+
+// Write replaces file with s.
+func (s *UserSnapshot) Write(file string) error {
+	return writeJSON(file, s)
+}
+
+// Write replaces file with s.
+func (s *GroupSnapshot) Write(file string) error {
+	return writeJSON(file, s)
+}
+
+func readJSON(file string, v interface{}) error {
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+	return nil
+}
+
+// writeJSON writes v indented with tabs like the snapshots in the
+// repository. Map keys are sorted by encoding/json, so unchanged content
+// produces identical files. The file is replaced atomically so readers
+// never see half a snapshot.
+func writeJSON(file string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(file), filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
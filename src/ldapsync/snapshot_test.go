@@ -0,0 +1,84 @@
+package ldapsync
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExportRoundTrip(t *testing.T) {
+	server := seedServer()
+	server.set(testUserBaseDN, userEntry("carol", "u3", "20200103000000Z"))
+	users, groups, err := server.directory(Options{}).Export(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	for name, u := range users.Users {
+		if u.VersionID != 1 {
+			t.Errorf("user %s has version %d, want 1", name, u.VersionID)
+		}
+	}
+	if len(users.Users) != 3 || len(groups.Groups) != 2 {
+		t.Fatalf("exported %d users and %d groups, want 3 and 2", len(users.Users), len(groups.Groups))
+	}
+
+	dir, err := ioutil.TempDir("", "snapshots")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	usersFile, groupsFile := filepath.Join(dir, "ldap-users.json"), filepath.Join(dir, "ldap-groups.json")
+	if err := users.Write(usersFile); err != nil {
+		t.Fatal(err)
+	}
+	if err := groups.Write(groupsFile); err != nil {
+		t.Fatal(err)
+	}
+	// Writing again replaces the files rather than failing or appending
+	if err := users.Write(usersFile); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		if f.Name() != "ldap-users.json" && f.Name() != "ldap-groups.json" {
+			t.Errorf("temporary file %s left behind", f.Name())
+		}
+		if f.Mode().Perm() != 0644 {
+			t.Errorf("%s has mode %v, want 0644", f.Name(), f.Mode().Perm())
+		}
+	}
+
+	readUsers, err := ReadUserSnapshot(usersFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readGroups, err := ReadGroupSnapshot(groupsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(readUsers, users) {
+		t.Errorf("users read back differ:\n%+v\nwant\n%+v", readUsers, users)
+	}
+	// The member DNs are not kept in the snapshot
+	for name, g := range groups.Groups {
+		g.Members = nil
+		groups.Groups[name] = g
+	}
+	if !reflect.DeepEqual(readGroups, groups) {
+		t.Errorf("groups read back differ:\n%+v\nwant\n%+v", readGroups, groups)
+	}
+}
+
+func TestReadMissingSnapshot(t *testing.T) {
+	users, err := ReadUserSnapshot(filepath.Join(os.TempDir(), "no-such-snapshot.json"))
+	if err != nil || users.Users == nil || len(users.Users) != 0 {
+		t.Errorf("ReadUserSnapshot of a missing file = %+v, %v, want an empty snapshot", users, err)
+	}
+}
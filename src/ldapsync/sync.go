@@ -0,0 +1,309 @@
+package ldapsync
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// generalizedTimeLayout formats modifyTimestamp bounds in filters.
+const generalizedTimeLayout = "20060102150405Z"
+
+// Changes lists the common names of the entries a sync added, updated and
+// deleted.
+type Changes struct {
+	Added   []string `json:"added"`
+	Updated []string `json:"updated"`
+	Deleted []string `json:"deleted"`
+}
+
+// Empty reports whether the sync changed nothing.
+func (c Changes) Empty() bool {
+	return len(c.Added) == 0 && len(c.Updated) == 0 && len(c.Deleted) == 0
+}
+
+func (c Changes) String() string {
+	return fmt.Sprintf("%d added, %d updated, %d deleted", len(c.Added), len(c.Updated), len(c.Deleted))
+}
+
+func (c *Changes) sort() {
+	sort.Strings(c.Added)
+	sort.Strings(c.Updated)
+	sort.Strings(c.Deleted)
+}
+
+// SyncResult reports what Sync changed in the snapshots.
+type SyncResult struct {
+	// Full is set when every entry was read, which is the only way
+	// deletions are detected.
+	Full   bool
+	Users  Changes
+	Groups Changes
+}
+
+// Sync brings the snapshots up to date with the directory. Unless full is
+// set, or a snapshot has never been synced, only entries with a
+// modifyTimestamp at or after the snapshot's LastModified are read and
+// merged into it; deleted entries stay in the snapshot until the next full
+// sync, which replaces the snapshots and reports the entries that are gone.
+// filter restricts both searches and may be empty.
+//
+// Entries are keyed by common name and matched by entryUUID, so a renamed
+// entry moves to its new key. VersionID starts at 1 and is incremented on
+// every change of an entry. The memberOf of the users read is completed
+// from the member lists of the groups, for servers that do not maintain
+// memberOf. Because adding a member to a group does not change the
+// modifyTimestamp of the user on every server, memberOf of the users not
+// read is patched from the member changes of the synced groups.
+func (d *Directory) Sync(ctx context.Context, users *UserSnapshot, groups *GroupSnapshot, filter string, full bool) (SyncResult, error) {
+	full = full || users.LastModified.IsZero() || groups.LastModified.IsZero()
+	result := SyncResult{Full: full}
+
+	before := make(map[string][]string, len(groups.Groups))
+	for name, g := range groups.Groups {
+		before[name] = g.Member
+	}
+
+	changes, err := d.syncGroups(ctx, groups, filter, full)
+	if err != nil {
+		return result, err
+	}
+	result.Groups = changes
+	if changes, err = d.syncUsers(ctx, users, memberships(groups), filter, full); err != nil {
+		return result, err
+	}
+	result.Users = changes
+
+	if !full {
+		patchMemberOf(users, groups, before, &result.Users)
+	}
+	result.Users.sort()
+	result.Groups.sort()
+	return result, nil
+}
+
+// modifiedSince restricts filter to entries modified at or after t. The
+// bound is inclusive because modifyTimestamp has a resolution of a second.
+func modifiedSince(filter string, t time.Time) string {
+	if filter == "" {
+		filter = DefaultFilter
+	}
+	return fmt.Sprintf("(&%s(modifyTimestamp>=%s))", filter, t.UTC().Format(generalizedTimeLayout))
+}
+
+// Export reads the whole directory into new snapshots, with every
+// VersionID set to 1.
+func (d *Directory) Export(ctx context.Context, filter string) (*UserSnapshot, *GroupSnapshot, error) {
+	users := &UserSnapshot{Users: map[string]User{}}
+	groups := &GroupSnapshot{Groups: map[string]Group{}}
+	if _, err := d.Sync(ctx, users, groups, filter, true); err != nil {
+		return nil, nil, err
+	}
+	return users, groups, nil
+}
+
+// memberships returns the names of the groups of each member.
+func memberships(s *GroupSnapshot) map[string][]string {
+	groups := map[string][]string{}
+	for name, g := range s.Groups {
+		for _, member := range g.Member {
+			groups[member] = append(groups[member], name)
+		}
+	}
+	return groups
+}
+
+// union returns the sorted values of a and b without duplicates, nil when
+// both are empty like a memberOf left out of a snapshot.
+func union(a, b []string) []string {
+	set := toSet(a)
+	for _, v := range b {
+		set[v] = true
+	}
+	if len(set) == 0 {
+		return nil
+	}
+	values := make([]string, 0, len(set))
+	for v := range set {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	return values
+}
+
+func (d *Directory) syncUsers(ctx context.Context, s *UserSnapshot, groups map[string][]string, filter string, full bool) (Changes, error) {
+	if !full {
+		filter = modifiedSince(filter, s.LastModified)
+	}
+	var changes Changes
+	byUUID := map[string]string{}
+	for name, u := range s.Users {
+		byUUID[u.EntryUUID] = name
+	}
+	seen := map[string]bool{}
+	err := d.EachUser(ctx, filter, func(u User) error {
+		name := u.CommonName
+		seen[name] = true
+		u.MemberOf = union(u.MemberOf, groups[name])
+		old, ok := s.Users[name]
+		if previous, renamed := byUUID[u.EntryUUID]; !ok && renamed && u.EntryUUID != "" {
+			old, ok = s.Users[previous], true
+			delete(s.Users, previous)
+			changes.Deleted = append(changes.Deleted, previous)
+		}
+		switch {
+		case !ok:
+			u.VersionID = 1
+			changes.Added = append(changes.Added, name)
+		default:
+			u.VersionID = old.VersionID
+			if !reflect.DeepEqual(u, old) {
+				u.VersionID++
+				changes.Updated = append(changes.Updated, name)
+			}
+		}
+		s.Users[name] = u
+		if u.ModifyTimestamp.After(s.LastModified) {
+			s.LastModified = u.ModifyTimestamp
+		}
+		return nil
+	})
+	if err != nil {
+		return changes, err
+	}
+	if full {
+		for name := range s.Users {
+			if !seen[name] {
+				delete(s.Users, name)
+				changes.Deleted = append(changes.Deleted, name)
+			}
+		}
+	}
+	return changes, nil
+}
+
+func (d *Directory) syncGroups(ctx context.Context, s *GroupSnapshot, filter string, full bool) (Changes, error) {
+	if !full {
+		filter = modifiedSince(filter, s.LastModified)
+	}
+	var changes Changes
+	byUUID := map[string]string{}
+	for name, g := range s.Groups {
+		byUUID[g.EntryUUID] = name
+	}
+	seen := map[string]bool{}
+	err := d.EachGroup(ctx, filter, func(g Group) error {
+		name := g.CommonName
+		seen[name] = true
+		old, ok := s.Groups[name]
+		if previous, renamed := byUUID[g.EntryUUID]; !ok && renamed && g.EntryUUID != "" {
+			old, ok = s.Groups[previous], true
+			delete(s.Groups, previous)
+			changes.Deleted = append(changes.Deleted, previous)
+		}
+		switch {
+		case !ok:
+			g.VersionID = 1
+			changes.Added = append(changes.Added, name)
+		default:
+			// The snapshot keeps the member names but not their DNs.
+			g.VersionID, old.Members = old.VersionID, g.Members
+			if !reflect.DeepEqual(g, old) {
+				g.VersionID++
+				changes.Updated = append(changes.Updated, name)
+			}
+		}
+		s.Groups[name] = g
+		if g.ModifyTimestamp.After(s.LastModified) {
+			s.LastModified = g.ModifyTimestamp
+		}
+		return nil
+	})
+	if err != nil {
+		return changes, err
+	}
+	if full {
+		for name := range s.Groups {
+			if !seen[name] {
+				delete(s.Groups, name)
+				changes.Deleted = append(changes.Deleted, name)
+			}
+		}
+	}
+	return changes, nil
+}
+
+// patchMemberOf adds the groups that gained a member to the memberOf of
+// the user and removes the groups that lost one.
+func patchMemberOf(users *UserSnapshot, groups *GroupSnapshot, before map[string][]string, changes *Changes) {
+	updated := map[string]bool{}
+	for _, name := range changes.Updated {
+		updated[name] = true
+	}
+	for _, name := range changes.Added {
+		updated[name] = true
+	}
+
+	patch := func(member, group string, add bool) {
+		u, ok := users.Users[member]
+		if !ok {
+			return
+		}
+		has := false
+		for i, g := range u.MemberOf {
+			if g == group {
+				has = true
+				if !add {
+					u.MemberOf = append(u.MemberOf[:i:i], u.MemberOf[i+1:]...)
+				}
+				break
+			}
+		}
+		if add == has {
+			return
+		}
+		if add {
+			u.MemberOf = append(u.MemberOf[:len(u.MemberOf):len(u.MemberOf)], group)
+			sort.Strings(u.MemberOf)
+		}
+		if !updated[member] {
+			u.VersionID++
+			updated[member] = true
+			changes.Updated = append(changes.Updated, member)
+		}
+		users.Users[member] = u
+	}
+
+	for name, g := range groups.Groups {
+		old := toSet(before[name])
+		for _, member := range g.Member {
+			if !old[member] {
+				patch(member, name, true)
+			}
+		}
+		current := toSet(g.Member)
+		for member := range old {
+			if !current[member] {
+				patch(member, name, false)
+			}
+		}
+	}
+	for name, members := range before {
+		if _, ok := groups.Groups[name]; ok {
+			continue
+		}
+		for _, member := range members {
+			patch(member, name, false)
+		}
+	}
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
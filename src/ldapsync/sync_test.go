@@ -0,0 +1,198 @@
+package ldapsync
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/ldap.v3"
+)
+
+// seedServer returns a server holding alice in readers and bob in
+// writers, all modified on 2020-01-01.
+func seedServer() *fakeServer {
+	s := newFakeServer()
+	s.set(testUserBaseDN, userEntry("alice", "u1", "20200101000000Z", "readers"))
+	s.set(testUserBaseDN, userEntry("bob", "u2", "20200101000000Z", "writers"))
+	s.set(testGroupBaseDN, groupEntry("readers", "g1", "20200101000000Z", "alice"))
+	s.set(testGroupBaseDN, groupEntry("writers", "g2", "20200101000000Z", "bob"))
+	return s
+}
+
+func withSurname(e *ldap.Entry, sn string) *ldap.Entry {
+	e.Attributes = append(e.Attributes, ldap.NewEntryAttribute("sn", []string{sn}))
+	return e
+}
+
+func TestSync(t *testing.T) {
+	seeded := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	changed := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		change func(s *fakeServer)
+		full   bool
+		users  Changes
+		groups Changes
+		// versions and memberOf are the expected users after the sync and
+		// modified the watermark of the user snapshot, which only moves
+		// with the users read
+		versions map[string]int
+		memberOf map[string][]string
+		groupSet []string
+		modified time.Time
+	}{
+		{
+			name:     "incremental without changes",
+			change:   func(s *fakeServer) {},
+			versions: map[string]int{"alice": 1, "bob": 1},
+			memberOf: map[string][]string{"alice": {"readers"}, "bob": {"writers"}},
+			groupSet: []string{"readers", "writers"},
+			modified: seeded,
+		},
+		{
+			name: "incremental with a changed user",
+			change: func(s *fakeServer) {
+				s.set(testUserBaseDN, withSurname(userEntry("bob", "u2", "20200102000000Z", "writers"), "Builder"))
+			},
+			users:    Changes{Updated: []string{"bob"}},
+			versions: map[string]int{"alice": 1, "bob": 2},
+			memberOf: map[string][]string{"alice": {"readers"}, "bob": {"writers"}},
+			groupSet: []string{"readers", "writers"},
+			modified: changed,
+		},
+		{
+			name: "incremental with a renamed user",
+			change: func(s *fakeServer) {
+				s.remove(testUserBaseDN, "cn=alice,"+testUserBaseDN)
+				s.set(testUserBaseDN, userEntry("alicia", "u1", "20200102000000Z", "readers"))
+				s.set(testGroupBaseDN, groupEntry("readers", "g1", "20200102000000Z", "alicia"))
+			},
+			users:    Changes{Updated: []string{"alicia"}, Deleted: []string{"alice"}},
+			groups:   Changes{Updated: []string{"readers"}},
+			versions: map[string]int{"alicia": 2, "bob": 1},
+			memberOf: map[string][]string{"alicia": {"readers"}, "bob": {"writers"}},
+			groupSet: []string{"readers", "writers"},
+			modified: changed,
+		},
+		{
+			name: "incremental with a member added to a group",
+			change: func(s *fakeServer) {
+				// The server does not touch the user, memberOf is patched
+				s.set(testGroupBaseDN, groupEntry("readers", "g1", "20200102000000Z", "alice", "bob"))
+			},
+			users:    Changes{Updated: []string{"bob"}},
+			groups:   Changes{Updated: []string{"readers"}},
+			versions: map[string]int{"alice": 1, "bob": 2},
+			memberOf: map[string][]string{"alice": {"readers"}, "bob": {"readers", "writers"}},
+			groupSet: []string{"readers", "writers"},
+			modified: seeded,
+		},
+		{
+			name: "incremental with a member removed from a group",
+			change: func(s *fakeServer) {
+				s.set(testGroupBaseDN, groupEntry("readers", "g1", "20200102000000Z", "bob"))
+			},
+			users:    Changes{Updated: []string{"alice", "bob"}},
+			groups:   Changes{Updated: []string{"readers"}},
+			versions: map[string]int{"alice": 2, "bob": 2},
+			memberOf: map[string][]string{"alice": {}, "bob": {"readers", "writers"}},
+			groupSet: []string{"readers", "writers"},
+			modified: seeded,
+		},
+		{
+			name: "incremental keeps deleted entries",
+			change: func(s *fakeServer) {
+				s.remove(testUserBaseDN, "cn=bob,"+testUserBaseDN)
+				s.remove(testGroupBaseDN, "cn=writers,"+testGroupBaseDN)
+			},
+			versions: map[string]int{"alice": 1, "bob": 1},
+			memberOf: map[string][]string{"alice": {"readers"}, "bob": {"writers"}},
+			groupSet: []string{"readers", "writers"},
+			modified: seeded,
+		},
+		{
+			name: "full sync drops deleted entries",
+			change: func(s *fakeServer) {
+				s.remove(testUserBaseDN, "cn=bob,"+testUserBaseDN)
+				s.remove(testGroupBaseDN, "cn=writers,"+testGroupBaseDN)
+			},
+			full:     true,
+			users:    Changes{Deleted: []string{"bob"}},
+			groups:   Changes{Deleted: []string{"writers"}},
+			versions: map[string]int{"alice": 1},
+			memberOf: map[string][]string{"alice": {"readers"}},
+			groupSet: []string{"readers"},
+			modified: seeded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := seedServer()
+			d := server.directory(Options{PageSize: 1})
+			users := &UserSnapshot{Users: map[string]User{}}
+			groups := &GroupSnapshot{Groups: map[string]Group{}}
+			first, err := d.Sync(context.Background(), users, groups, "", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !first.Full || !users.LastModified.Equal(seeded) || !groups.LastModified.Equal(seeded) {
+				t.Fatalf("first sync full %v, watermarks %v and %v", first.Full, users.LastModified, groups.LastModified)
+			}
+
+			tt.change(server)
+			server.requests = nil
+			result, err := d.Sync(context.Background(), users, groups, "", tt.full)
+			if err != nil {
+				t.Fatalf("Sync: %v", err)
+			}
+
+			if result.Full != tt.full {
+				t.Errorf("Full = %v, want %v", result.Full, tt.full)
+			}
+			if !reflect.DeepEqual(result.Users, tt.users) {
+				t.Errorf("user changes = %+v, want %+v", result.Users, tt.users)
+			}
+			if !reflect.DeepEqual(result.Groups, tt.groups) {
+				t.Errorf("group changes = %+v, want %+v", result.Groups, tt.groups)
+			}
+			for _, req := range server.requests {
+				bounded := strings.Contains(req.Filter, "(modifyTimestamp>=20200101000000Z)")
+				if bounded == tt.full {
+					t.Errorf("filter %s of a sync with full %v", req.Filter, tt.full)
+				}
+			}
+
+			versions := map[string]int{}
+			memberOf := map[string][]string{}
+			for name, u := range users.Users {
+				versions[name] = u.VersionID
+				memberOf[name] = u.MemberOf
+			}
+			if !reflect.DeepEqual(versions, tt.versions) {
+				t.Errorf("versions = %v, want %v", versions, tt.versions)
+			}
+			if !reflect.DeepEqual(memberOf, tt.memberOf) {
+				t.Errorf("memberOf = %v, want %v", memberOf, tt.memberOf)
+			}
+			if got := sortedNames(groups.Groups); !reflect.DeepEqual(got, tt.groupSet) {
+				t.Errorf("groups = %q, want %q", got, tt.groupSet)
+			}
+			if !users.LastModified.Equal(tt.modified) {
+				t.Errorf("users LastModified = %v, want %v", users.LastModified, tt.modified)
+			}
+		})
+	}
+}
+
+func sortedNames(groups map[string]Group) []string {
+	var names []string
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
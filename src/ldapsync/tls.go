@@ -0,0 +1,51 @@
+package ldapsync
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+// ErrInsecureBind is returned when bind credentials would be sent over a
+// connection that is not encrypted and Options.AllowInsecureBind is false.
+var ErrInsecureBind = errors.New("refusing to send bind credentials over an unencrypted connection")
+
+// TLSOptions configures the TLS connection to the directory.
+type TLSOptions struct {
+	// CAFile is a PEM bundle of the certificate authorities trusted to sign
+	// the server certificate, the system roots when empty.
+	CAFile string
+	// ServerName overrides the host name the server certificate is checked
+	// against, e.g. when connecting through a load balancer by IP.
+	ServerName string
+	// CertFile and KeyFile are the PEM client certificate and key presented
+	// to servers that require one.
+	CertFile string
+	KeyFile  string
+}
+
+// Config returns the tls.Config for o.
+func (o TLSOptions) Config() (*tls.Config, error) {
+	config := &tls.Config{ServerName: o.ServerName, MinVersion: tls.VersionTLS12}
+	if o.CAFile != "" {
+		pem, err := ioutil.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", o.CAFile)
+		}
+		config.RootCAs = pool
+	}
+	if o.CertFile != "" || o.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
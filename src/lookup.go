@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ashish246/GolangGitExample/src/ldapsync"
+)
+
+// LookupSnapshot prints the groups of a user or the members of a group
+// found in the snapshots, without connecting to the directory.
+func LookupSnapshot(opts lookupOptions, w io.Writer) error {
+	index, err := ldapsync.LoadIndex(opts.UsersFile, opts.GroupsFile)
+	if err != nil {
+		return err
+	}
+
+	var user ldapsync.User
+	var ok bool
+	switch {
+	case opts.User != "":
+		user, ok = index.User(opts.User)
+	case opts.Account != "":
+		user, ok = index.UserBySAMAccountName(opts.Account)
+	}
+	if opts.User != "" || opts.Account != "" {
+		if !ok {
+			return fmt.Errorf("no user %s%s in %s", opts.User, opts.Account, opts.UsersFile)
+		}
+		fmt.Fprintf(w, "User %s (%s)\n", user.CommonName, user.SAMAccountName)
+		if opts.Nested {
+			for _, m := range index.NestedGroupsOf(user.CommonName) {
+				fmt.Fprintf(w, "  %s%s\n", m.Group.CommonName, via(m.Via))
+			}
+			return nil
+		}
+		for _, g := range index.GroupsOf(user.CommonName) {
+			fmt.Fprintf(w, "  %s\n", g.CommonName)
+		}
+		return nil
+	}
+
+	var group ldapsync.Group
+	if opts.Group != "" {
+		group, ok = index.Group(opts.Group)
+	} else {
+		group, ok = index.GroupByEntryUUID(opts.GroupUUID)
+	}
+	if !ok {
+		return fmt.Errorf("no group %s%s in %s", opts.Group, opts.GroupUUID, opts.GroupsFile)
+	}
+	fmt.Fprintf(w, "Group %s (%s)\n", group.CommonName, group.EntryUUID)
+	members := index.MembersOf(group.CommonName)
+	if opts.Nested {
+		members = index.NestedMembersOf(group.CommonName)
+	}
+	for _, u := range members {
+		fmt.Fprintf(w, "  %s (%s)\n", u.CommonName, u.SAMAccountName)
+	}
+	return nil
+}
+
+// via describes the groups a nested membership goes through.
+func via(groups []string) string {
+	if len(groups) == 0 {
+		return ""
+	}
+	return " (via " + strings.Join(groups, " > ") + ")"
+}
+
+// CheckSnapshots prints the inconsistencies between the user and group
+// snapshots and fails when there are any, for use in CI.
+func CheckSnapshots(opts checkOptions, w io.Writer) error {
+	users, err := ldapsync.ReadUserSnapshot(opts.UsersFile)
+	if err != nil {
+		return err
+	}
+	groups, err := ldapsync.ReadGroupSnapshot(opts.GroupsFile)
+	if err != nil {
+		return err
+	}
+
+	problems := ldapsync.Check(users, groups)
+	for _, p := range problems {
+		fmt.Fprintln(w, p)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%d inconsistencies between %s and %s", len(problems), opts.UsersFile, opts.GroupsFile)
+	}
+	fmt.Fprintf(w, "%d users and %d groups are consistent\n", len(users.Users), len(groups.Groups))
+	return nil
+}
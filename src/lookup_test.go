@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLookupSnapshot(t *testing.T) {
+	tests := []struct {
+		name string
+		opts lookupOptions
+		want string
+	}{
+		{
+			name: "groups of a user",
+			opts: lookupOptions{Account: "cspusr22"},
+			want: "User CSPUsr22 (CSPUsr22)\n  AU Digital CSP Support\n",
+		},
+		{
+			name: "members of a group",
+			opts: lookupOptions{GroupUUID: "b6469dd8-361f-1037-8b58-65eead392ae4"},
+			want: "Group AU Digital BDS Users (b6469dd8-361f-1037-8b58-65eead392ae4)\n  CSPUsr24 (CSPUsr24)\n",
+		},
+	}
+	for _, tt := range tests {
+		tt.opts.UsersFile, tt.opts.GroupsFile = "../ldap-users.json", "../ldap-groups.json"
+		var out bytes.Buffer
+		if err := LookupSnapshot(tt.opts, &out); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if out.String() != tt.want {
+			t.Errorf("%s: printed\n%s\nwant\n%s", tt.name, out.String(), tt.want)
+		}
+	}
+
+	opts := lookupOptions{UsersFile: "../ldap-users.json", GroupsFile: "../ldap-groups.json", User: "admin1"}
+	if err := LookupSnapshot(opts, &bytes.Buffer{}); err == nil {
+		t.Error("LookupSnapshot of an unknown user succeeded")
+	}
+}
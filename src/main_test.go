@@ -0,0 +1,102 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+func TestTartar(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	staging := filepath.Join(dir, "staging")
+	files := map[string][]byte{
+		"data.json":          []byte("{}"),
+		"uam2/policy.rego":   []byte("package uam2\n"),
+		"uam2/common/a.rego": []byte("package uam2.common\n"),
+	}
+	if err := writeFiles(staging, files); err != nil {
+		t.Fatal(err)
+	}
+
+	tarName := filepath.Join(dir, "bundle.tar.gz")
+	if err := Tartar(staging, tarName); err != nil {
+		t.Fatalf("Tartar: %v", err)
+	}
+	f, err := os.Open(tarName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		got[hdr.Name], _ = ioutil.ReadAll(tr)
+	}
+	if !reflect.DeepEqual(got, files) {
+		t.Errorf("bundle holds %q, want %q", got, files)
+	}
+
+	// A failed write is returned, not fatal, so bundle watch survives it
+	if err := Tartar(staging, filepath.Join(dir, "missing", "bundle.tar.gz")); err == nil {
+		t.Error("Tartar into a missing folder succeeded")
+	}
+}
+
+func TestBuildBundleKeepsStagingDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// The staging folder is shared, e.g. with the input of git publish
+	keep := filepath.Join(dir, "generated.json")
+	if err := ioutil.WriteFile(keep, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	data := filepath.Join(dir, "data.json")
+	if err := ioutil.WriteFile(data, []byte(`{"a": 1}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := bundleOptions{DataFile: data, StagingDir: dir, TarFile: filepath.Join(dir, "bundle.tar.gz")}
+	fetched := []policyFiles{{
+		source: "policy",
+		commit: &object.Commit{Hash: plumbing.NewHash("0123456789abcdef0123456789abcdef01234567")},
+		files:  map[string][]byte{"policy.rego": []byte("package policy\n")},
+	}}
+	if err := buildBundle(opts, fetched); err != nil {
+		t.Fatalf("buildBundle: %v", err)
+	}
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	want := []string{"bundle.tar.gz", "data.json", "generated.json"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("staging folder holds %v after the build, want %v", names, want)
+	}
+}
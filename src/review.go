@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/ashish246/GolangGitExample/src/codehost"
+	"github.com/ashish246/GolangGitExample/src/gitstore"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// reviewOptions selects whether the commits of the git subcommands are
+// pushed straight to the branch or proposed in a pull request.
+type reviewOptions struct {
+	PullRequest  bool
+	BranchPrefix string
+	// DiffFile is the path of an entitlements file in the repository whose
+	// changes are added to the pull request description.
+	DiffFile string
+
+	host codehost.Host
+}
+
+func (o *reviewOptions) register(fs *flag.FlagSet, cfg *Config) {
+	fs.BoolVar(&o.PullRequest, "pull-request", cfg.Publish.Mode == publishPullRequest, "open a pull request against -branch instead of pushing to it")
+	fs.StringVar(&o.BranchPrefix, "branch-prefix", cfg.Publish.BranchPrefix, "prefix of the branch pushed for a pull request")
+	fs.StringVar(&o.DiffFile, "diff-entitlements", "", "entitlements file in the repository whose changes are described in the pull request")
+}
+
+// resolve loads the code host when pull requests are opened.
+func (o *reviewOptions) resolve(cfg *Config) error {
+	if !o.PullRequest {
+		return nil
+	}
+	if o.BranchPrefix == "" {
+		return fmt.Errorf("-branch-prefix must not be empty with -pull-request")
+	}
+	host, err := cfg.PullRequestHost()
+	if err != nil {
+		return err
+	}
+	o.host = host
+	return nil
+}
+
+// apply commits the files returned by update on top of the checked out
+// branch. The commit is pushed to the branch, retrying as Update does, or
+// with o.PullRequest pushed to a new branch named after the commit and
+// proposed for the checked out branch in a pull request. A nil commit is
+// returned when nothing changed.
+func (o reviewOptions) apply(repo *gitstore.PolicyRepo, base string, update gitstore.UpdateFunc, opts gitstore.CommitOptions) (*object.Commit, error) {
+	if !o.PullRequest {
+		return repo.Update(update, opts)
+	}
+
+	files, err := update(repo)
+	if err != nil {
+		return nil, err
+	}
+	commit, err := repo.CommitFiles(files, opts)
+	if err != nil || commit == nil {
+		return nil, err
+	}
+
+	branch := o.BranchPrefix + commit.Hash.String()[:12]
+	if err := repo.PushBranch(branch); err != nil {
+		return nil, err
+	}
+	title, body := splitMessage(commit.Message)
+	if o.DiffFile != "" {
+		diff, err := o.diff(repo, commit)
+		if err != nil {
+			return nil, err
+		}
+		body = strings.TrimSpace(body + "\n\n" + diff.Markdown())
+	}
+	url, err := o.host.OpenPullRequest(context.Background(), codehost.PullRequest{
+		Head:  branch,
+		Base:  base,
+		Title: title,
+		Body:  body,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pull request for %s: %v", branch, err)
+	}
+	fmt.Printf("Opened pull request %s\n", url)
+	return commit, nil
+}
+
+// diff compares o.DiffFile between commit and its parent.
+func (o reviewOptions) diff(repo *gitstore.PolicyRepo, commit *object.Commit) (EntitlementDiff, error) {
+	var from LdapGroupEntitlements
+	fromLabel := "(none)"
+	if commit.NumParents() > 0 {
+		parent, err := commit.Parent(0)
+		if err != nil {
+			return EntitlementDiff{}, fmt.Errorf("failed to get parent of %s: %v", commit.Hash, err)
+		}
+		if from, err = entitlementsAt(repo, parent, o.DiffFile); err != nil {
+			return EntitlementDiff{}, err
+		}
+		fromLabel = parent.Hash.String()[:12]
+	}
+	to, err := entitlementsAt(repo, commit, o.DiffFile)
+	if err != nil {
+		return EntitlementDiff{}, err
+	}
+	return DiffEntitlements(fromLabel, from, commit.Hash.String()[:12], to), nil
+}
+
+// splitMessage returns the subject line and the body of a commit message.
+func splitMessage(message string) (string, string) {
+	parts := strings.SplitN(strings.TrimSpace(message), "\n", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], strings.TrimSpace(parts[1])
+}
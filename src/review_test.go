@@ -0,0 +1,157 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ashish246/GolangGitExample/src/codehost"
+	"github.com/ashish246/GolangGitExample/src/gitstore"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+const reviewEntitlements = `version: '1.0'
+ldap_groups:
+    - name: AU Digital CSP Support
+      roles:
+        - name: support
+          entitlement_groups:
+            - name: support
+              entitlements:
+                - read
+`
+
+var reviewCommit = gitstore.CommitOptions{
+	Author:  &object.Signature{Name: "Policy Bot", Email: "policy-bot@example.com", When: time.Unix(1500000000, 0)},
+	Message: gitstore.Message("Update entitlements\n\nGenerated from LDAP."),
+}
+
+// newRemote returns the file:// URL of a bare repository whose master
+// branch holds files in one commit.
+func newRemote(t *testing.T, files map[string][]byte) string {
+	dir, err := ioutil.TempDir("", "remote")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	if _, err := git.PlainInit(dir, true); err != nil {
+		t.Fatal(err)
+	}
+	url := "file://" + dir
+
+	repo, err := gitstore.Init(gitstore.Options{URL: url, Branch: "master"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CommitFiles(files, reviewCommit); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Push(); err != nil {
+		t.Fatal(err)
+	}
+	return url
+}
+
+// remoteBranch returns the commit branch points at in the repository at
+// url, or the zero hash when it does not exist.
+func remoteBranch(t *testing.T, url, branch string) plumbing.Hash {
+	repo, err := git.PlainOpen(strings.TrimPrefix(url, "file://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err == plumbing.ErrReferenceNotFound {
+		return plumbing.ZeroHash
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ref.Hash()
+}
+
+func TestReviewApplyPullRequest(t *testing.T) {
+	url := newRemote(t, map[string][]byte{"README.md": []byte("policies\n")})
+	base := remoteBranch(t, url, "master")
+	repo, err := gitstore.Open(gitstore.Options{URL: url, Branch: "master"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	host := &codehost.Fake{}
+	opts := reviewOptions{PullRequest: true, BranchPrefix: "opa-publish/", DiffFile: "entitlements.yml", host: host}
+	files := map[string][]byte{"entitlements.yml": []byte(reviewEntitlements)}
+	commit, err := opts.apply(repo, "master", gitstore.Files(files), reviewCommit)
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if commit == nil {
+		t.Fatal("apply returned no commit")
+	}
+
+	head := "opa-publish/" + commit.Hash.String()[:12]
+	if got := remoteBranch(t, url, head); got != commit.Hash {
+		t.Errorf("remote %s = %s, want %s", head, got, commit.Hash)
+	}
+	if got := remoteBranch(t, url, "master"); got != base {
+		t.Errorf("master moved to %s, want it left at %s", got, base)
+	}
+
+	pulls := host.PullRequests()
+	if len(pulls) != 1 {
+		t.Fatalf("opened %d pull requests, want 1", len(pulls))
+	}
+	pr := pulls[0]
+	if pr.Head != head || pr.Base != "master" || pr.Title != "Update entitlements" {
+		t.Errorf("pull request head %q base %q title %q", pr.Head, pr.Base, pr.Title)
+	}
+	if !strings.HasPrefix(pr.Body, "Generated from LDAP.") || !strings.Contains(pr.Body, "AU Digital CSP Support") {
+		t.Errorf("pull request body lacks the message or the entitlements diff:\n%s", pr.Body)
+	}
+}
+
+func TestReviewApplyUnchanged(t *testing.T) {
+	files := map[string][]byte{"entitlements.yml": []byte(reviewEntitlements)}
+	url := newRemote(t, files)
+	repo, err := gitstore.Open(gitstore.Options{URL: url, Branch: "master"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	host := &codehost.Fake{}
+	opts := reviewOptions{PullRequest: true, BranchPrefix: "opa-publish/", host: host}
+	commit, err := opts.apply(repo, "master", gitstore.Files(files), reviewCommit)
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if commit != nil {
+		t.Errorf("apply committed %s for unchanged files", commit.Hash)
+	}
+	if n := len(host.PullRequests()); n != 0 {
+		t.Errorf("opened %d pull requests for unchanged files", n)
+	}
+}
+
+func TestReviewApplyPush(t *testing.T) {
+	url := newRemote(t, map[string][]byte{"README.md": []byte("policies\n")})
+	repo, err := gitstore.Open(gitstore.Options{URL: url, Branch: "master"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	host := &codehost.Fake{}
+	files := map[string][]byte{"entitlements.yml": []byte(reviewEntitlements)}
+	commit, err := reviewOptions{host: host}.apply(repo, "master", gitstore.Files(files), reviewCommit)
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if got := remoteBranch(t, url, "master"); commit == nil || got != commit.Hash {
+		t.Errorf("master = %s, want the new commit", got)
+	}
+	if n := len(host.PullRequests()); n != 0 {
+		t.Errorf("opened %d pull requests without -pull-request", n)
+	}
+}
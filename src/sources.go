@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ashish246/GolangGitExample/src/credentials"
+	"github.com/ashish246/GolangGitExample/src/gitstore"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// ConflictError is returned when two policy sources, or a source and a file
+// generated for the bundle, write the same path in the bundle.
+type ConflictError struct {
+	Path   string
+	First  string
+	Second string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("bundle path %s is written by both %s and %s", e.Path, e.First, e.Second)
+}
+
+// policyFiles are the files taken from one policy source, keyed by their
+// path in the bundle.
+type policyFiles struct {
+	source string
+	commit *object.Commit
+	files  map[string][]byte
+}
+
+// collectFiles reads the files selected by opts from repo, checking out
+// opts.Ref first when set.
+func collectFiles(repo *gitstore.PolicyRepo, opts fetchOptions) (policyFiles, error) {
+	source := opts.source()
+	rev := opts.Branch
+	head, err := repo.Head()
+	if opts.Ref != "" {
+		rev = opts.Ref
+		head, err = repo.Checkout(opts.Ref)
+	}
+	if err != nil {
+		return policyFiles{}, err
+	}
+	if opts.VerifyKeyRing != "" {
+		keyRing, err := ioutil.ReadFile(opts.VerifyKeyRing)
+		if err != nil {
+			return policyFiles{}, fmt.Errorf("failed to read keyring: %v", err)
+		}
+		entity, err := gitstore.Verify(head, string(keyRing))
+		if err != nil {
+			return policyFiles{}, fmt.Errorf("refusing to use %s of %s: %v", rev, source, err)
+		}
+		fmt.Printf("Good signature on %s from key %s\n", head.Hash, entity.PrimaryKey.KeyIdString())
+	}
+
+	dir := path.Join(".", opts.Path)
+	names, err := repo.Glob(dir, opts.Include, opts.Exclude)
+	if err != nil {
+		return policyFiles{}, err
+	}
+	if len(names) == 0 {
+		return policyFiles{}, fmt.Errorf("no files below %s in %s of %s at %s match %v", dir, rev, source, head.Hash, opts.Include)
+	}
+	fmt.Printf("Fetching %d files from %s of %s at %s\n", len(names), rev, source, head.Hash)
+
+	files := make(map[string][]byte, len(names))
+	for _, name := range names {
+		content, err := repo.ReadFile(path.Join(dir, name))
+		if err != nil {
+			return policyFiles{}, err
+		}
+		files[path.Join(opts.Root, name)] = content
+	}
+	return policyFiles{source: source, commit: head, files: files}, nil
+}
+
+// fetchSources clones every source concurrently and collects its files. The
+// results are in the order of sources. The server's progress messages are
+// only shown for a single source, since concurrent clones would interleave
+// them.
+func fetchSources(sources []fetchOptions) ([]policyFiles, error) {
+	fetched := make([]policyFiles, len(sources))
+	errs := make([]error, len(sources))
+
+	var wg sync.WaitGroup
+	for i, source := range sources {
+		wg.Add(1)
+		go func(i int, source fetchOptions) {
+			defer wg.Done()
+			store := source.storeOptions()
+			if len(sources) > 1 {
+				store.Progress = nil
+			}
+			repo, err := gitstore.Open(store)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			fetched[i], errs[i] = collectFiles(repo, source)
+		}(i, source)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch source %s: %w", sources[i].source(), err)
+		}
+	}
+	return fetched, nil
+}
+
+// mergeSources combines the files of all sources into one tree. Writing a
+// path twice, or one of the reserved paths, is a ConflictError.
+func mergeSources(fetched []policyFiles, reserved ...string) (map[string][]byte, error) {
+	owners := map[string]string{}
+	for _, name := range reserved {
+		owners[name] = "the bundle build"
+	}
+
+	merged := map[string][]byte{}
+	for _, f := range fetched {
+		names := make([]string, 0, len(f.files))
+		for name := range f.files {
+			names = append(names, name)
+		}
+		// Sorted so the same conflict is reported on every run
+		sort.Strings(names)
+		for _, name := range names {
+			if owner, ok := owners[name]; ok {
+				return nil, &ConflictError{Path: name, First: owner, Second: "source " + f.source}
+			}
+			owners[name] = "source " + f.source
+			merged[name] = f.files[name]
+		}
+	}
+	return merged, nil
+}
+
+// revision identifies the commits a bundle was built from: the hash of the
+// only commit, or name:hash pairs when there are several sources.
+func revision(fetched []policyFiles) string {
+	if len(fetched) == 1 {
+		return fetched[0].commit.Hash.String()
+	}
+	revs := make([]string, len(fetched))
+	for i, f := range fetched {
+		revs[i] = f.source + ":" + f.commit.Hash.String()
+	}
+	return strings.Join(revs, ",")
+}
+
+// writeFiles writes files below dir, creating folders as needed.
+func writeFiles(dir string, files map[string][]byte) error {
+	for name, content := range files {
+		dest := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return fmt.Errorf("failed to create staging folder: %v", err)
+		}
+		if err := ioutil.WriteFile(dest, content, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// source names the source in messages, by its name if it has one.
+func (o fetchOptions) source() string {
+	if o.Name != "" {
+		return o.Name
+	}
+	return credentials.RedactURL(o.URL)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ashish246/GolangGitExample/src/gitstore"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+func TestMergeSources(t *testing.T) {
+	common := policyFiles{source: "common", files: map[string][]byte{
+		"common/lib.rego":  []byte("package common"),
+		"common/util.rego": []byte("package common.util"),
+	}}
+	team := policyFiles{source: "team", files: map[string][]byte{
+		"uam2/policy.rego": []byte("package uam2"),
+	}}
+	clash := policyFiles{source: "clash", files: map[string][]byte{
+		"common/lib.rego": []byte("package clash"),
+	}}
+	manifest := policyFiles{source: "team", files: map[string][]byte{
+		".manifest": []byte("{}"),
+	}}
+
+	tests := []struct {
+		name     string
+		fetched  []policyFiles
+		reserved []string
+		want     map[string][]byte
+		conflict *ConflictError
+	}{
+		{
+			name:     "disjoint roots",
+			fetched:  []policyFiles{common, team},
+			reserved: []string{"data.json", ".manifest"},
+			want: map[string][]byte{
+				"common/lib.rego":  []byte("package common"),
+				"common/util.rego": []byte("package common.util"),
+				"uam2/policy.rego": []byte("package uam2"),
+			},
+		},
+		{
+			name:     "same path twice",
+			fetched:  []policyFiles{common, team, clash},
+			conflict: &ConflictError{Path: "common/lib.rego", First: "source common", Second: "source clash"},
+		},
+		{
+			name:     "reserved path",
+			fetched:  []policyFiles{manifest},
+			reserved: []string{"data.json", ".manifest"},
+			conflict: &ConflictError{Path: ".manifest", First: "the bundle build", Second: "source team"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergeSources(tt.fetched, tt.reserved...)
+			if tt.conflict != nil {
+				var conflict *ConflictError
+				if !errors.As(err, &conflict) || *conflict != *tt.conflict {
+					t.Fatalf("mergeSources error = %v, want %v", err, tt.conflict)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("mergeSources: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeSources = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRevision(t *testing.T) {
+	a := &object.Commit{Hash: plumbing.NewHash("1111111111111111111111111111111111111111")}
+	b := &object.Commit{Hash: plumbing.NewHash("2222222222222222222222222222222222222222")}
+	tests := []struct {
+		fetched []policyFiles
+		want    string
+	}{
+		{
+			fetched: []policyFiles{{source: "common", commit: a}},
+			want:    "1111111111111111111111111111111111111111",
+		},
+		{
+			fetched: []policyFiles{{source: "common", commit: a}, {source: "team", commit: b}},
+			want:    "common:1111111111111111111111111111111111111111,team:2222222222222222222222222222222222222222",
+		},
+	}
+	for _, tt := range tests {
+		if got := revision(tt.fetched); got != tt.want {
+			t.Errorf("revision = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestCollectFilesMountsAtRoot(t *testing.T) {
+	url := newRemote(t, map[string][]byte{
+		"lib/common.rego":      []byte("package common"),
+		"lib/common_test.rego": []byte("package common_test"),
+		"README.md":            []byte("policies\n"),
+	})
+	repo, err := gitstore.Open(gitstore.Options{URL: url, Branch: "master"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := fetchOptions{
+		Name:    "common",
+		Path:    "lib",
+		Root:    "common",
+		Include: []string{"**/*.rego"},
+		Exclude: []string{"**/*_test.rego"},
+	}
+	opts.URL = url
+	opts.Branch = "master"
+	fetched, err := collectFiles(repo, opts)
+	if err != nil {
+		t.Fatalf("collectFiles: %v", err)
+	}
+	want := map[string][]byte{"common/common.rego": []byte("package common")}
+	if !reflect.DeepEqual(fetched.files, want) {
+		t.Errorf("collectFiles = %q, want %q", fetched.files, want)
+	}
+	if fetched.source != "common" || fetched.commit == nil {
+		t.Errorf("collectFiles source %q commit %v", fetched.source, fetched.commit)
+	}
+}
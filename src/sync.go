@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/ashish246/GolangGitExample/src/ldapsync"
+)
+
+// syncState is persisted in snapshot.state_file between runs of ldap sync.
+type syncState struct {
+	LastFullSync time.Time `json:"last_full_sync"`
+}
+
+// SyncSnapshots updates the user and group snapshots from the directory,
+// reading everything when -full is given or the last full sync is older
+// than -full-interval, and prints what changed.
+func SyncSnapshots(opts syncOptions) error {
+	var state syncState
+	content, err := ioutil.ReadFile(opts.StateFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		if err := json.Unmarshal(content, &state); err != nil {
+			return fmt.Errorf("failed to parse %s: %v", opts.StateFile, err)
+		}
+	}
+
+	users, err := ldapsync.ReadUserSnapshot(opts.UsersFile)
+	if err != nil {
+		return err
+	}
+	groups, err := ldapsync.ReadGroupSnapshot(opts.GroupsFile)
+	if err != nil {
+		return err
+	}
+
+	full := opts.Full || time.Since(state.LastFullSync) >= opts.FullInterval
+	started := time.Now().UTC()
+	result, err := opts.directory().Sync(context.Background(), users, groups, opts.Filter, full)
+	if err != nil {
+		return err
+	}
+
+	if err := users.Write(opts.UsersFile); err != nil {
+		return err
+	}
+	if err := groups.Write(opts.GroupsFile); err != nil {
+		return err
+	}
+	if result.Full {
+		state.LastFullSync = started
+		content, err := json.MarshalIndent(state, "", "\t")
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(opts.StateFile, content, 0644); err != nil {
+			return err
+		}
+	}
+
+	kind := "Incremental"
+	if result.Full {
+		kind = "Full"
+	}
+	fmt.Printf("%s sync up to %s\n", kind, users.LastModified.Format(time.RFC3339))
+	printChanges("Users", result.Users)
+	printChanges("Groups", result.Groups)
+	return nil
+}
+
+func printChanges(title string, c ldapsync.Changes) {
+	fmt.Printf("%s: %s\n", title, c)
+	for _, name := range c.Added {
+		fmt.Printf("  + %s\n", name)
+	}
+	for _, name := range c.Updated {
+		fmt.Printf("  ~ %s\n", name)
+	}
+	for _, name := range c.Deleted {
+		fmt.Printf("  - %s\n", name)
+	}
+}
+
+// ExportSnapshots replaces the user and group snapshots with the whole
+// directory.
+func ExportSnapshots(opts exportOptions) error {
+	users, groups, err := opts.directory().Export(context.Background(), opts.Filter)
+	if err != nil {
+		return err
+	}
+	if err := users.Write(opts.UsersFile); err != nil {
+		return err
+	}
+	if err := groups.Write(opts.GroupsFile); err != nil {
+		return err
+	}
+	fmt.Printf("Exported %d users to %s and %d groups to %s\n", len(users.Users), opts.UsersFile, len(groups.Groups), opts.GroupsFile)
+	return nil
+}
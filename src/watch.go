@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ashish246/GolangGitExample/src/gitstore"
+	"github.com/ashish246/GolangGitExample/src/watch"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// WatchBundle builds the bundle, then rebuilds it whenever a new commit on
+// the configured branch changes a file below opts.Paths. The branch is
+// polled every opts.Interval and, with opts.Listen set, checked whenever a
+// push webhook is posted to /webhook. It runs until interrupted.
+func WatchBundle(opts watchOptions) error {
+	repo, err := gitstore.Open(opts.Bundle.Fetch.storeOptions())
+	if err != nil {
+		return err
+	}
+	build := func() error {
+		fetched, err := collectFiles(repo, opts.Bundle.Fetch)
+		if err != nil {
+			return err
+		}
+		return buildBundle(opts.Bundle, []policyFiles{fetched})
+	}
+	if err := build(); err != nil {
+		return err
+	}
+
+	w := watch.New(repo, func(head *object.Commit, changed []string) error {
+		fmt.Printf("Rebuilding bundle for %s, changed: %v\n", head.Hash, changed)
+		return build()
+	})
+	w.Paths = opts.Paths
+	w.Interval = opts.Interval
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if opts.Listen != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/webhook", w.Webhook(opts.secret))
+		server := &http.Server{Addr: opts.Listen, Handler: mux}
+		go func() {
+			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("webhook listener: %v", err)
+				cancel()
+			}
+		}()
+		defer server.Close()
+		fmt.Printf("Listening for push webhooks on %s/webhook\n", opts.Listen)
+	}
+
+	fmt.Printf("Watching %s for changes to %v\n", opts.Bundle.Fetch.Branch, opts.Paths)
+	err = w.Run(ctx)
+	if err == context.Canceled {
+		return nil
+	}
+	return err
+}
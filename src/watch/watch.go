@@ -0,0 +1,142 @@
+// Package watch follows the branch of a policy repository, by polling or
+// when told to by a push webhook, and reports new commits that change files
+// below the watched paths.
+package watch
+
+import (
+	"context"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ashish246/GolangGitExample/src/gitstore"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// ChangeFunc is called with the new tip of the branch and the changed files
+// below the watched paths.
+type ChangeFunc func(head *object.Commit, changed []string) error
+
+// Watcher checks the branch of a policy repository for new commits.
+type Watcher struct {
+	// Paths are the slash separated files and folders whose changes are
+	// reported. A commit changing nothing below them is skipped; no paths
+	// watch the whole repository.
+	Paths []string
+	// Interval between two checks of the remote branch. With zero the
+	// branch is only checked when Trigger is called.
+	Interval time.Duration
+	// ErrorLog receives the errors of failed checks, which are retried on
+	// the next one. It defaults to the standard logger.
+	ErrorLog *log.Logger
+
+	repo     *gitstore.PolicyRepo
+	onChange ChangeFunc
+	trigger  chan struct{}
+}
+
+// New returns a Watcher calling onChange when the branch checked out in
+// repo moves. The commit checked out when Run is called is the starting
+// point.
+func New(repo *gitstore.PolicyRepo, onChange ChangeFunc) *Watcher {
+	return &Watcher{
+		repo:     repo,
+		onChange: onChange,
+		trigger:  make(chan struct{}, 1),
+	}
+}
+
+// Trigger makes Run check the branch now. Calls made while a check is
+// pending are merged into it.
+func (w *Watcher) Trigger() {
+	select {
+	case w.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Run checks the branch every Interval and whenever Trigger is called,
+// until ctx is done. When the tip moved and changed a watched file,
+// onChange is called; if it fails the same changes are reported again on
+// the next check.
+func (w *Watcher) Run(ctx context.Context) error {
+	last, err := w.repo.Head()
+	if err != nil {
+		return err
+	}
+
+	var tick <-chan time.Time
+	if w.Interval > 0 {
+		ticker := time.NewTicker(w.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick:
+		case <-w.trigger:
+		}
+
+		head, err := w.check(last)
+		if err != nil {
+			w.logf("watch %s: %v", w.repo.Branch(), err)
+			continue
+		}
+		last = head
+	}
+}
+
+// check fetches the branch and reports the changes since last. It returns
+// the commit the next check starts from.
+func (w *Watcher) check(last *object.Commit) (*object.Commit, error) {
+	if err := w.repo.Reset(); err != nil {
+		return last, err
+	}
+	head, err := w.repo.Head()
+	if err != nil || head.Hash == last.Hash {
+		return last, err
+	}
+
+	files, err := gitstore.ChangedFiles(last, head)
+	if err != nil {
+		return last, err
+	}
+	var changed []string
+	for _, file := range files {
+		if w.watched(file) {
+			changed = append(changed, file)
+		}
+	}
+	if len(changed) == 0 {
+		return head, nil
+	}
+	if err := w.onChange(head, changed); err != nil {
+		return last, err
+	}
+	return head, nil
+}
+
+func (w *Watcher) watched(file string) bool {
+	if len(w.Paths) == 0 {
+		return true
+	}
+	for _, p := range w.Paths {
+		p = path.Clean(p)
+		if p == "." || file == p || strings.HasPrefix(file, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *Watcher) logf(format string, args ...interface{}) {
+	if w.ErrorLog != nil {
+		w.ErrorLog.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
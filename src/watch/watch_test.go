@@ -0,0 +1,252 @@
+package watch
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ashish246/GolangGitExample/src/gitstore"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+var testCommit = gitstore.CommitOptions{
+	Author:  &object.Signature{Name: "Policy Bot", Email: "policy-bot@example.com", When: time.Unix(1500000000, 0)},
+	Message: gitstore.Message("Update policies"),
+}
+
+// newRemote returns the file:// URL of a bare repository whose master
+// branch holds files in one commit.
+func newRemote(t *testing.T, files map[string][]byte) string {
+	dir, err := ioutil.TempDir("", "remote")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	if _, err := git.PlainInit(dir, true); err != nil {
+		t.Fatal(err)
+	}
+	url := "file://" + dir
+
+	p, err := gitstore.Init(gitstore.Options{URL: url, Branch: "master"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.CommitFiles(files, testCommit); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Push(); err != nil {
+		t.Fatal(err)
+	}
+	return url
+}
+
+func openRemote(t *testing.T, url string) *gitstore.PolicyRepo {
+	p, err := gitstore.Open(gitstore.Options{URL: url, Branch: "master"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// push commits files on top of the remote branch from another clone.
+func push(t *testing.T, url string, files map[string][]byte) *object.Commit {
+	p := openRemote(t, url)
+	commit, err := p.CommitFiles(files, testCommit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Push(); err != nil {
+		t.Fatal(err)
+	}
+	return commit
+}
+
+// changes records the calls of a ChangeFunc.
+type changes struct {
+	heads   []*object.Commit
+	changed [][]string
+	err     error
+}
+
+func (c *changes) onChange(head *object.Commit, changed []string) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.heads = append(c.heads, head)
+	c.changed = append(c.changed, changed)
+	return nil
+}
+
+func TestCheck(t *testing.T) {
+	url := newRemote(t, map[string][]byte{
+		"README.md":   []byte("policies\n"),
+		"uam2/a.rego": []byte("package a\n"),
+	})
+	repo := openRemote(t, url)
+	start, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c changes
+	w := New(repo, c.onChange)
+	w.Paths = []string{"uam2/"}
+
+	// Nothing pushed
+	last, err := w.check(start)
+	if err != nil || last.Hash != start.Hash || len(c.heads) != 0 {
+		t.Fatalf("check without a new commit = %v, %v, %d changes", last, err, len(c.heads))
+	}
+
+	// A commit outside the watched paths moves the starting point silently
+	readme := push(t, url, map[string][]byte{"README.md": []byte("policies, updated\n")})
+	last, err = w.check(last)
+	if err != nil || last.Hash != readme.Hash {
+		t.Fatalf("check = %v, %v, want %s", last, err, readme.Hash)
+	}
+	if len(c.heads) != 0 {
+		t.Fatalf("reported %v for a commit outside the watched paths", c.changed)
+	}
+
+	// A failed rebuild reports the same changes again on the next check
+	policy := push(t, url, map[string][]byte{"uam2/b.rego": []byte("package b\n")})
+	c.err = errors.New("build failed")
+	if last, err = w.check(last); err == nil || last.Hash != readme.Hash {
+		t.Fatalf("check with a failing ChangeFunc = %v, %v", last, err)
+	}
+	c.err = nil
+	last, err = w.check(last)
+	if err != nil || last.Hash != policy.Hash {
+		t.Fatalf("check = %v, %v, want %s", last, err, policy.Hash)
+	}
+	if len(c.heads) != 1 || c.heads[0].Hash != policy.Hash {
+		t.Fatalf("reported heads %v, want %s", c.heads, policy.Hash)
+	}
+	if want := []string{"uam2/b.rego"}; !reflect.DeepEqual(c.changed[0], want) {
+		t.Errorf("changed = %q, want %q", c.changed[0], want)
+	}
+}
+
+func TestWatched(t *testing.T) {
+	tests := []struct {
+		paths []string
+		file  string
+		want  bool
+	}{
+		{nil, "README.md", true},
+		{[]string{"."}, "README.md", true},
+		{[]string{"uam2"}, "uam2/a.rego", true},
+		{[]string{"uam2/"}, "uam2/nested/a.rego", true},
+		{[]string{"uam2"}, "uam2.rego", false},
+		{[]string{"uam2"}, "uam20/a.rego", false},
+		{[]string{"uam2", "opa-policy.rego"}, "opa-policy.rego", true},
+		{[]string{"uam2", "opa-policy.rego"}, "README.md", false},
+	}
+	for _, tt := range tests {
+		w := &Watcher{Paths: tt.paths}
+		if got := w.watched(tt.file); got != tt.want {
+			t.Errorf("watched(%q) with paths %q = %v, want %v", tt.file, tt.paths, got, tt.want)
+		}
+	}
+}
+
+func TestRunOnTrigger(t *testing.T) {
+	url := newRemote(t, map[string][]byte{"uam2/a.rego": []byte("package a\n")})
+	repo := openRemote(t, url)
+	heads := make(chan *object.Commit, 1)
+	w := New(repo, func(head *object.Commit, changed []string) error {
+		heads <- head
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+
+	commit := push(t, url, map[string][]byte{"uam2/a.rego": []byte("package a.v2\n")})
+	w.Trigger()
+	select {
+	case head := <-heads:
+		if head.Hash != commit.Hash {
+			t.Errorf("reported %s, want %s", head.Hash, commit.Hash)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("no change reported after Trigger")
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Run = %v, want context.Canceled", err)
+	}
+}
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebhook(t *testing.T) {
+	url := newRemote(t, map[string][]byte{"README.md": []byte("policies\n")})
+	w := New(openRemote(t, url), nil)
+	server := httptest.NewServer(w.Webhook("s3cret"))
+	defer server.Close()
+
+	payload := `{"ref": "refs/heads/master"}`
+	other := `{"ref": "refs/heads/feature"}`
+	tests := []struct {
+		name      string
+		method    string
+		body      string
+		signature string
+		status    int
+		triggered bool
+	}{
+		{"get", http.MethodGet, "", "", http.StatusMethodNotAllowed, false},
+		{"unsigned", http.MethodPost, payload, "", http.StatusUnauthorized, false},
+		{"bad signature", http.MethodPost, payload, sign("guess", payload), http.StatusUnauthorized, false},
+		{"malformed signature", http.MethodPost, payload, "sha256=zz", http.StatusUnauthorized, false},
+		{"other ref", http.MethodPost, other, sign("s3cret", other), http.StatusNoContent, false},
+		{"ping", http.MethodPost, `{"zen": "hi"}`, sign("s3cret", `{"zen": "hi"}`), http.StatusAccepted, true},
+		{"push", http.MethodPost, payload, sign("s3cret", payload), http.StatusAccepted, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, server.URL, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.signature != "" {
+				req.Header.Set("X-Hub-Signature-256", tt.signature)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+
+			triggered := false
+			select {
+			case <-w.trigger:
+				triggered = true
+			default:
+			}
+			if triggered != tt.triggered {
+				t.Errorf("triggered = %v, want %v", triggered, tt.triggered)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package watch
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/ashish246/GolangGitExample/src/credentials"
+)
+
+// maxPayload bounds the size of a webhook request body.
+const maxPayload = 5 << 20
+
+// Webhook returns a handler for push webhooks that triggers w when the
+// watched branch was pushed to. Payloads naming another ref are ignored.
+// With a non-empty secret, requests must carry a valid GitHub style
+// X-Hub-Signature-256 header, an HMAC-SHA256 of the body keyed with secret.
+func (w *Watcher) Webhook(secret credentials.Secret) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			rw.Header().Set("Allow", http.MethodPost)
+			http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := ioutil.ReadAll(http.MaxBytesReader(rw, r.Body, maxPayload))
+		if err != nil {
+			http.Error(rw, "failed to read payload", http.StatusBadRequest)
+			return
+		}
+		if secret != "" && !validSignature(secret, body, r.Header.Get("X-Hub-Signature-256")) {
+			http.Error(rw, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		var push struct {
+			Ref string `json:"ref"`
+		}
+		// Payloads without a ref, such as pings, trigger a check as well
+		_ = json.Unmarshal(body, &push)
+		if push.Ref != "" && push.Ref != "refs/heads/"+w.repo.Branch() {
+			rw.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Trigger()
+		rw.WriteHeader(http.StatusAccepted)
+	})
+}
+
+func validSignature(secret credentials.Secret, body []byte, header string) bool {
+	const prefix = "sha256="
+	if !strings.HasPrefix(header, prefix) {
+		return false
+	}
+	got, err := hex.DecodeString(strings.TrimPrefix(header, prefix))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret.Reveal()))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}